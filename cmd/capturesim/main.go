@@ -0,0 +1,266 @@
+package main
+
+// capturesim: Simula a gravação (celular/tela) de um vídeo NCC tocando e
+// verifica o decode no modo capture. Sem FFmpeg: frames via .nccv e
+// captura em .y4m.
+//
+//	go run ./cmd/capturesim -size=65536 -capture-fps=50 -persp=0.06
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	mrand "math/rand"
+	"os"
+	"path/filepath"
+
+	"ncc/internal/decoder"
+	"ncc/internal/encoder"
+)
+
+// simOptions: Parâmetros da simulação (flags)
+type simOptions struct {
+	input      string  // Arquivo a codificar (vazio = dados aleatórios)
+	size       int     // Tamanho dos dados aleatórios
+	preset     string  // Preset do encoder
+	captureFPS float64 // FPS da gravação
+	playSpeed  float64 // Velocidade de reprodução
+	persp      float64 // Distorção de perspectiva (fração da tela)
+	moire      float64 // Amplitude da grade de pixels do monitor
+	noise      float64 // Ruído do sensor (desvio padrão)
+	seed       int64   // Semente da simulação
+	keep       bool    // Manter arquivos temporários
+}
+
+func main() {
+	var opts simOptions
+	flag.StringVar(&opts.input, "input", "", "Arquivo a codificar (vazio = dados aleatórios)")
+	flag.IntVar(&opts.size, "size", 16*1024, "Tamanho dos dados aleatórios")
+	flag.StringVar(&opts.preset, "preset", "default", "Preset do encoder")
+	flag.Float64Var(&opts.captureFPS, "capture-fps", 60, "FPS da gravação")
+	flag.Float64Var(&opts.playSpeed, "speed", 1, "Velocidade de reprodução (0.5 = metade)")
+	flag.Float64Var(&opts.persp, "persp", 0.05, "Distorção de perspectiva (fração da tela)")
+	flag.Float64Var(&opts.moire, "moire", 0.2, "Amplitude da grade de pixels do monitor")
+	flag.Float64Var(&opts.noise, "noise", 6, "Ruído do sensor (desvio padrão)")
+	flag.Int64Var(&opts.seed, "seed", 1, "Semente da simulação")
+	flag.BoolVar(&opts.keep, "keep", false, "Manter arquivos temporários")
+	flag.Parse()
+
+	n, err := simulate(opts)
+	if err != nil {
+		fail(err)
+	}
+	fmt.Printf("✅ SUCCESS! %d bytes recuperados da captura\n", n)
+}
+
+// simulate: Encode, gravação simulada e decode no modo capture. Retorna os
+// bytes recuperados (iguais ao original) ou o erro.
+func simulate(opts simOptions) (int, error) {
+	rng := mrand.New(mrand.NewSource(opts.seed))
+
+	tempDir, err := os.MkdirTemp("", "ncc-capturesim-*")
+	if err != nil {
+		return 0, err
+	}
+	if !opts.keep {
+		defer os.RemoveAll(tempDir)
+	} else {
+		fmt.Printf("📁 Temporários em %s\n", tempDir)
+	}
+
+	// 1. Dados de entrada
+	input := opts.input
+	if input == "" {
+		data := make([]byte, opts.size)
+		rand.Read(data)
+		input = filepath.Join(tempDir, "input.bin")
+		if err := os.WriteFile(input, data, 0644); err != nil {
+			return 0, err
+		}
+	}
+	original, err := os.ReadFile(input)
+	if err != nil {
+		return 0, err
+	}
+
+	// 2. Encode para .nccv (Go puro)
+	enc, err := encoder.NewVideoEncoder("medium", 0, opts.preset, "none")
+	if err != nil {
+		return 0, err
+	}
+	defer enc.Cleanup()
+	nccvPath := filepath.Join(tempDir, "video.nccv")
+	if err := enc.EncodeFile(input, nccvPath, nil); err != nil {
+		return 0, err
+	}
+	frames, w, h, err := readNCCV(nccvPath)
+	if err != nil {
+		return 0, err
+	}
+	fmt.Printf("🎬 %d frames %dx%d a %d FPS\n", len(frames), w, h, enc.FrameCfg.FPS)
+
+	// 3. Gravação simulada: vídeo em parte do quadro 1920x1080 com
+	// perspectiva, moiré, exposição variável, ruído e frames misturados
+	// (FPS da câmera != FPS do vídeo)
+	capCfg := encoder.FrameConfig{Width: 1920, Height: 1080, FPS: int(math.Round(opts.captureFPS))}
+	capPath := filepath.Join(tempDir, "capture.y4m")
+	capEnc := &encoder.VideoEncoder{FrameCfg: capCfg}
+	sink, err := capEnc.OpenSink(capPath, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	playFPS := float64(enc.FrameCfg.FPS) * opts.playSpeed
+	duration := float64(len(frames)) / playFPS
+	captures := int(duration * opts.captureFPS)
+	exposure := 0.5 / opts.captureFPS // Obturador aberto metade do intervalo
+	phase := rng.Float64() / opts.captureFPS
+
+	// Quadrilátero do vídeo no quadro (~70% da largura, centralizado)
+	jitter := func() float64 { return (rng.Float64()*2 - 1) * opts.persp * float64(capCfg.Width) }
+	quad := [4][2]float64{
+		{290 + jitter(), 160 + jitter()},
+		{1630 + jitter(), 160 + jitter()},
+		{290 + jitter(), 920 + jitter()},
+		{1630 + jitter(), 920 + jitter()},
+	}
+	ref := [4][2]float64{{0, 0}, {float64(w), 0}, {0, float64(h)}, {float64(w), float64(h)}}
+	toFrame := solveHomography(quad, ref)
+
+	canvas := make([]byte, capCfg.Width*capCfg.Height)
+	blend := make([]float64, w*h)
+	for k := 0; k < captures; k++ {
+		// Mistura temporal: média dos frames exibidos durante a exposição
+		t0 := phase + float64(k)/opts.captureFPS
+		for i := range blend {
+			blend[i] = 0
+		}
+		const steps = 8
+		for s := 0; s < steps; s++ {
+			idx := min(int((t0+exposure*float64(s)/steps)*playFPS), len(frames)-1)
+			for i, v := range frames[idx] {
+				blend[i] += float64(v) / steps
+			}
+		}
+
+		gain := 0.85 + 0.25*math.Sin(float64(k)/9) // Auto-exposição oscilando
+		for y := 0; y < capCfg.Height; y++ {
+			for x := 0; x < capCfg.Width; x++ {
+				u, v := toFrame.apply(float64(x)+0.5, float64(y)+0.5)
+				val := 25 + 10*float64(y)/float64(capCfg.Height) // Fundo escuro
+				if u >= 0 && v >= 0 && u < float64(w) && v < float64(h) {
+					// Grade de pixels do monitor amostrada pela câmera = moiré
+					grid := 1 - opts.moire*(0.5-0.5*math.Cos(2*math.Pi*u)*math.Cos(2*math.Pi*v))
+					val = (20 + 0.85*blend[int(v)*w+int(u)]) * grid
+				}
+				val = val*gain + rng.NormFloat64()*opts.noise
+				canvas[y*capCfg.Width+x] = uint8(math.Max(0, math.Min(255, val)))
+			}
+		}
+		if err := sink.WriteFrame(canvas); err != nil {
+			sink.Close()
+			return 0, err
+		}
+	}
+	if err := sink.Close(); err != nil {
+		return 0, err
+	}
+	fmt.Printf("📷 %d capturas a %.0f FPS (vídeo a %.1f FPS)\n", captures, opts.captureFPS, playFPS)
+
+	// 4. Decode no modo capture
+	src, err := decoder.OpenSource(capPath)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+	recon := decoder.NewFrameReconstructor(opts.preset)
+	recon.Capture = true
+	outPath := filepath.Join(tempDir, "recovered.bin")
+	if err := recon.ReconstructSource(src, outPath, nil); err != nil {
+		return 0, err
+	}
+	recovered, err := os.ReadFile(outPath)
+	if err != nil {
+		return 0, err
+	}
+	if !bytes.Equal(recovered, original) {
+		return 0, fmt.Errorf("dados recuperados diferem (%d de %d bytes)", len(recovered), len(original))
+	}
+	return len(recovered), nil
+}
+
+// readNCCV: Frames Y do container .nccv
+func readNCCV(path string) ([][]byte, int, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer f.Close()
+
+	var header [encoder.NCCVHeaderSize]byte
+	if _, err := io.ReadFull(f, header[:]); err != nil {
+		return nil, 0, 0, fmt.Errorf("read nccv header: %w", err)
+	}
+	w := int(binary.BigEndian.Uint32(header[8:12]))
+	h := int(binary.BigEndian.Uint32(header[12:16]))
+	count := int(binary.BigEndian.Uint32(header[20:24]))
+
+	frames := make([][]byte, count)
+	for i := range frames {
+		frames[i] = make([]byte, w*h)
+		if _, err := io.ReadFull(f, frames[i]); err != nil {
+			return nil, 0, 0, fmt.Errorf("read nccv frame %d: %w", i, err)
+		}
+	}
+	return frames, w, h, nil
+}
+
+type homography [9]float64
+
+func (m homography) apply(x, y float64) (float64, float64) {
+	d := m[6]*x + m[7]*y + m[8]
+	return (m[0]*x + m[1]*y + m[2]) / d, (m[3]*x + m[4]*y + m[5]) / d
+}
+
+// solveHomography: Homografia que leva src[i] em dst[i] (DLT, 4 pares)
+func solveHomography(src, dst [4][2]float64) homography {
+	var a [8][9]float64
+	for i := 0; i < 4; i++ {
+		x, y := src[i][0], src[i][1]
+		u, v := dst[i][0], dst[i][1]
+		a[2*i] = [9]float64{x, y, 1, 0, 0, 0, -u * x, -u * y, u}
+		a[2*i+1] = [9]float64{0, 0, 0, x, y, 1, -v * x, -v * y, v}
+	}
+	for col := 0; col < 8; col++ {
+		pivot := col
+		for r := col + 1; r < 8; r++ {
+			if math.Abs(a[r][col]) > math.Abs(a[pivot][col]) {
+				pivot = r
+			}
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		for r := 0; r < 8; r++ {
+			if r != col {
+				f := a[r][col] / a[col][col]
+				for c := col; c < 9; c++ {
+					a[r][c] -= f * a[col][c]
+				}
+			}
+		}
+	}
+	var m homography
+	for i := 0; i < 8; i++ {
+		m[i] = a[i][8] / a[i][i]
+	}
+	m[8] = 1
+	return m
+}
+
+func fail(err error) {
+	fmt.Printf("❌ Error: %v\n", err)
+	os.Exit(1)
+}
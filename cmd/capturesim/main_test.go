@@ -0,0 +1,39 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSolveHomography(t *testing.T) {
+	src := [4][2]float64{{310, 150}, {1650, 190}, {280, 930}, {1600, 900}}
+	dst := [4][2]float64{{0, 0}, {1280, 0}, {0, 720}, {1280, 720}}
+	m := solveHomography(src, dst)
+	for i := range src {
+		u, v := m.apply(src[i][0], src[i][1])
+		if math.Abs(u-dst[i][0]) > 1e-6 || math.Abs(v-dst[i][1]) > 1e-6 {
+			t.Errorf("canto %d: (%.3f, %.3f), want %v", i, u, v, dst[i])
+		}
+	}
+}
+
+func TestSimulateCapture(t *testing.T) {
+	if testing.Short() {
+		t.Skip("simulação de captura (1080p por captura)")
+	}
+	opts := simOptions{size: 4096, preset: "default", captureFPS: 60, playSpeed: 1, persp: 0.05, moire: 0.2, noise: 6, seed: 1}
+	n, err := simulate(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != opts.size {
+		t.Fatalf("%d bytes recuperados, want %d", n, opts.size)
+	}
+
+	// Reprodução rápida demais para a câmera: frames perdidos são erro,
+	// não dados errados
+	opts.playSpeed, opts.captureFPS = 4, 20
+	if _, err := simulate(opts); err == nil {
+		t.Error("captura com frames perdidos decodificada sem erro")
+	}
+}
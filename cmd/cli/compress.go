@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"ncc/internal/trailer"
+)
+
+// compressChunk: Bytes do original por membro gzip. Cada membro é
+// independente e leva no campo Extra (subcampo "NC") o offset do seu
+// trecho: um leitor gzip comum lê tudo como um fluxo só (multistream) e,
+// com -partial, um trecho perdido custa só os membros que o tocam.
+const compressChunk = 1 << 20
+
+var gzipExtraID = [2]byte{'N', 'C'}
+
+// errNoOffset: Membro sem o subcampo "NC" (payload antigo, gzip único)
+var errNoOffset = errors.New("membro gzip sem offset")
+
+func compressData(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	for off := 0; ; off += compressChunk {
+		end := min(off+compressChunk, len(data))
+		gz.Reset(&buf)
+		gz.Header.Extra = memberExtra(int64(off))
+		if _, err := gz.Write(data[off:end]); err != nil {
+			return nil, err
+		}
+		if err := gz.Close(); err != nil {
+			return nil, err
+		}
+		if end == len(data) {
+			return buf.Bytes(), nil
+		}
+	}
+}
+
+// memberExtra: Subcampo RFC 1952 "NC" | tamanho u16 (LE) | offset u64
+func memberExtra(off int64) []byte {
+	extra := append(gzipExtraID[:], 8, 0)
+	return binary.BigEndian.AppendUint64(extra, uint64(off))
+}
+
+// memberOffset: Offset do subcampo "NC" do campo Extra
+func memberOffset(extra []byte) (int64, bool) {
+	for len(extra) >= 4 {
+		size := int(binary.LittleEndian.Uint16(extra[2:4]))
+		if len(extra) < 4+size {
+			break
+		}
+		if [2]byte(extra[:2]) == gzipExtraID && size == 8 {
+			return int64(binary.BigEndian.Uint64(extra[4:12])), true
+		}
+		extra = extra[4+size:]
+	}
+	return 0, false
+}
+
+// countingReader: Conta os bytes entregues ao gzip. Com ReadByte o flate lê
+// direto dele (sem buffer próprio), então a contagem é exatamente o tamanho
+// do membro.
+type countingReader struct {
+	r *bufio.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
+
+// readMember: Membro gzip que começa em pos: offset no original, bytes
+// comprimidos consumidos e dados (CRC do gzip conferido)
+func readMember(src io.ReaderAt, pos, size int64) (int64, int64, []byte, error) {
+	cr := &countingReader{r: bufio.NewReader(io.NewSectionReader(src, pos, size-pos))}
+	gz, err := gzip.NewReader(cr)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	gz.Multistream(false)
+	off, ok := memberOffset(gz.Header.Extra)
+	if !ok {
+		return 0, 0, nil, errNoOffset
+	}
+	data, err := io.ReadAll(io.LimitReader(gz, compressChunk+1))
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	if len(data) > compressChunk || off < 0 {
+		return 0, 0, nil, fmt.Errorf("membro gzip inválido")
+	}
+	return off, cr.n, data, nil
+}
+
+// nextMember: Próximo cabeçalho gzip (1f 8b 08) a partir de from, ou size
+func nextMember(src io.ReaderAt, from, size int64) int64 {
+	magic := []byte{0x1f, 0x8b, 0x08}
+	buf := make([]byte, 64*1024)
+	for from < size {
+		n, _ := src.ReadAt(buf[:min(int64(len(buf)), size-from)], from)
+		if i := bytes.Index(buf[:n], magic); i >= 0 {
+			return from + int64(i)
+		}
+		if n < len(magic) {
+			break
+		}
+		from += int64(n - len(magic) + 1)
+	}
+	return size
+}
+
+// recoverMembers: -partial: descomprime o fluxo em src membro a membro,
+// gravando cada membro íntegro no seu offset em out; um membro ilegível
+// (lacuna zerada, CRC) é pulado e a leitura retoma no próximo cabeçalho.
+// Retorna os trechos do original recuperados. Payload antigo (gzip único,
+// sem offsets): só o início íntegro.
+func recoverMembers(src io.ReaderAt, size int64, out io.WriterAt) ([]trailer.Range, error) {
+	var recovered []trailer.Range
+	for pos := int64(0); pos < size; {
+		off, n, data, err := readMember(src, pos, size)
+		if errors.Is(err, errNoOffset) && pos == 0 {
+			return recoverPrefix(src, size, out)
+		}
+		if err != nil {
+			pos = nextMember(src, pos+1, size)
+			continue
+		}
+		if _, err := out.WriteAt(data, off); err != nil {
+			return nil, err
+		}
+		recovered = append(recovered, trailer.Range{Start: off, End: off + int64(len(data))})
+		pos += n
+	}
+	return trailer.MergeRanges(recovered), nil
+}
+
+// recoverPrefix: Gzip único: descomprime até o primeiro erro
+func recoverPrefix(src io.ReaderAt, size int64, out io.WriterAt) ([]trailer.Range, error) {
+	fmt.Fprintln(os.Stderr, "⚠️  Payload em gzip único (versão antiga): só o início íntegro é recuperável")
+	gz, err := gzip.NewReader(io.NewSectionReader(src, 0, size))
+	if err != nil {
+		return nil, nil
+	}
+	n, err := io.Copy(io.NewOffsetWriter(out, 0), gz)
+	if err != nil && n == 0 {
+		return nil, nil
+	}
+	return []trailer.Range{{Start: 0, End: n}}, nil
+}
+
+// recoverPayload: -partial: fluxo comprimido (já decifrado) em gzPath ->
+// arquivo final em path, com os trechos perdidos zerados. Só com o arquivo
+// inteiro recuperado o manifest é conferido.
+func recoverPayload(path, gzPath string, manifest *trailer.Manifest) error {
+	src, err := os.Open(gzPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmpPath := path + ".part"
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("salvar arquivo final: %w", err)
+	}
+	recovered, err := recoverMembers(src, info.Size(), out)
+	if err != nil {
+		out.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("salvar arquivo final: %w", err)
+	}
+
+	// Tamanho: o do manifest ou o fim do último trecho recuperado
+	var total int64
+	if n := len(recovered); n > 0 {
+		total = recovered[n-1].End
+	}
+	if manifest != nil {
+		total = int64(manifest.Size)
+	} else if total%compressChunk == 0 {
+		fmt.Fprintln(os.Stderr, "⚠️  Sem manifest: o fim do arquivo pode ter se perdido")
+	}
+	if err := out.Truncate(total); err != nil {
+		out.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("salvar arquivo final: %w", err)
+	}
+
+	lost := missingRanges(recovered, total)
+	if len(lost) > 0 {
+		fmt.Fprintf(os.Stderr, "⚠️  Trechos do arquivo perdidos, zerados na saída (bytes): %s\n", formatRanges(lost))
+	} else if manifest != nil {
+		h := sha256.New()
+		if _, err := io.Copy(h, io.NewSectionReader(out, 0, total)); err != nil {
+			out.Close()
+			os.Remove(tmpPath)
+			return fmt.Errorf("salvar arquivo final: %w", err)
+		}
+		if [32]byte(h.Sum(nil)) != manifest.SHA256 {
+			out.Close()
+			os.Remove(tmpPath)
+			return fmt.Errorf("INTEGRIDADE: SHA-256 do arquivo recuperado não confere com o manifest")
+		}
+		fmt.Println("✅ Integrity verified (SHA-256 manifest)")
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("salvar arquivo final: %w", err)
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// missingRanges: Complemento dos intervalos recuperados (ordenados) em
+// [0, total)
+func missingRanges(recovered []trailer.Range, total int64) []trailer.Range {
+	var lost []trailer.Range
+	var pos int64
+	for _, r := range recovered {
+		if r.Start > pos {
+			lost = append(lost, trailer.Range{Start: pos, End: min(r.Start, total)})
+		}
+		pos = max(pos, r.End)
+	}
+	if pos < total {
+		lost = append(lost, trailer.Range{Start: pos, End: total})
+	}
+	return lost
+}
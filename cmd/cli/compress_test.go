@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"math/rand"
+	"testing"
+
+	"ncc/internal/trailer"
+)
+
+// writerAt: io.WriterAt em memória
+type writerAt []byte
+
+func (w writerAt) WriteAt(p []byte, off int64) (int, error) {
+	return copy(w[off:], p), nil
+}
+
+func TestCompressDataMultistream(t *testing.T) {
+	for _, size := range []int{0, 1, compressChunk, 2*compressChunk + 17} {
+		data := make([]byte, size)
+		rand.New(rand.NewSource(int64(size))).Read(data)
+		comp, err := compressData(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gz, err := gzip.NewReader(bytes.NewReader(comp))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(gz)
+		if err != nil {
+			t.Fatalf("%d bytes: %v", size, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("%d bytes: leitor gzip comum não reproduz a entrada", size)
+		}
+	}
+}
+
+func TestRecoverMembersSkipsDamage(t *testing.T) {
+	data := make([]byte, 3*compressChunk+1000)
+	rand.New(rand.NewSource(1)).Read(data)
+	comp, err := compressData(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Lacuna zerada no meio do segundo membro
+	_, n, _, err := readMember(bytes.NewReader(comp), 0, int64(len(comp)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy(comp[n+1000:], make([]byte, 5000))
+
+	out := make(writerAt, len(data))
+	recovered, err := recoverMembers(bytes.NewReader(comp), int64(len(comp)), out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lost := missingRanges(recovered, int64(len(data)))
+	want := []trailer.Range{{Start: compressChunk, End: 2 * compressChunk}}
+	if len(lost) != 1 || lost[0] != want[0] {
+		t.Fatalf("perdidos = %v, want %v", lost, want)
+	}
+	for _, r := range recovered {
+		if !bytes.Equal(out[r.Start:r.End], data[r.Start:r.End]) {
+			t.Fatalf("trecho %s recuperado difere", r)
+		}
+	}
+}
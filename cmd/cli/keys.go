@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"ncc/internal/audio"
+	"ncc/internal/crypto"
+	"ncc/internal/encoder"
+)
+
+// stringList: Flag repetível (-recipient a -recipient b)
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// keyOptions: Segredos e chaves para cifrar/decifrar o payload
+type keyOptions struct {
+	password   string
+	recipients []string // -recipient: chave pública "ncc-pub-..." ou arquivo com chaves
+	identities []string // -identity: arquivos de identidade (ncc keygen)
+	kdfMemory  int      // -kdf-memory: MiB (0 = padrão)
+	kdfTime    int      // -kdf-time: iterações (0 = padrão)
+	signKey    string   // -sign: arquivo da chave Ed25519 (encode)
+	verifyKey  string   // -verify: chave "ncc-sig-..." ou arquivo (decode)
+	scramble   bool     // -scramble: frames embaralhados com chave derivada da chave de arquivo
+	split      string   // -shares: "k-of-n" partes Shamir da chave de arquivo (encode)
+	sharePages bool     // -share-pages: partes também em páginas para impressão
+	shares     []string // -share: partes "NCC-SHARE-..." ou arquivos (decode)
+
+	archive *crypto.ArchiveKeys // Decode: header já aberto (openArchive)
+}
+
+// encrypted: Encode cifra o payload
+func (k keyOptions) encrypted() bool {
+	return k.password != "" || len(k.recipients) > 0 || k.split != ""
+}
+
+// checkShares: -shares é o único destinatário: uma senha ou chave pública
+// junto abriria o arquivo sem as k partes
+func (k keyOptions) checkShares() error {
+	if k.split != "" && (k.password != "" || len(k.recipients) > 0) {
+		return fmt.Errorf("-shares não combina com senha (-password*, -ask-password) nem -recipient")
+	}
+	return nil
+}
+
+// decrypting: Decode recebeu senha, -identity ou -share (exige payload
+// cifrado)
+func (k keyOptions) decrypting() bool {
+	return k.password != "" || len(k.identities) > 0 || len(k.shares) > 0
+}
+
+// cryptoRecipients: Senha e chaves públicas como destinatários NCC3
+func (k keyOptions) cryptoRecipients() ([]crypto.Recipient, error) {
+	var out []crypto.Recipient
+	if k.password != "" {
+		params := crypto.DefaultKDFParams()
+		var err error
+		if k.kdfMemory > 0 {
+			if params.Memory, err = crypto.KDFMemoryMiB(k.kdfMemory); err != nil {
+				return nil, fmt.Errorf("-kdf-memory: %w", err)
+			}
+		}
+		if k.kdfTime > 0 {
+			if params.Time, err = crypto.KDFTime(k.kdfTime); err != nil {
+				return nil, fmt.Errorf("-kdf-time: %w", err)
+			}
+		}
+		r, err := crypto.NewPasswordRecipientKDF(k.password, params)
+		if err != nil {
+			return nil, fmt.Errorf("-kdf-memory/-kdf-time: %w", err)
+		}
+		if params != crypto.DefaultKDFParams() {
+			fmt.Printf("🔑 KDF: %s\n", params)
+		}
+		out = append(out, r)
+	}
+	for _, arg := range k.recipients {
+		if strings.HasPrefix(arg, crypto.PublicKeyPrefix) {
+			r, err := crypto.ParseRecipient(arg)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, r)
+			continue
+		}
+		f, err := os.Open(arg)
+		if err != nil {
+			return nil, fmt.Errorf("-recipient: %w", err)
+		}
+		rs, err := crypto.ParseRecipients(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("-recipient %s: %w", arg, err)
+		}
+		for _, r := range rs {
+			out = append(out, r)
+		}
+	}
+	if k.split != "" {
+		r, err := parseShareSpec(k.split)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// cryptoIdentities: Senha e arquivos de identidade para o decode (só a
+// chave já aberta, se houver)
+func (k keyOptions) cryptoIdentities() ([]crypto.Identity, error) {
+	if k.archive != nil {
+		return []crypto.Identity{k.archive.Identity()}, nil
+	}
+	var out []crypto.Identity
+	if k.password != "" {
+		out = append(out, crypto.NewPasswordIdentity(k.password))
+	}
+	for _, path := range k.identities {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("-identity: %w", err)
+		}
+		ids, err := crypto.ParseIdentities(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("-identity %s: %w", path, err)
+		}
+		for _, id := range ids {
+			out = append(out, id)
+		}
+	}
+	if len(k.shares) > 0 {
+		shares, err := loadShares(k.shares)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, crypto.NewShareIdentity(shares))
+	}
+	return out, nil
+}
+
+// encryptPayload: NCC3 para todos os destinatários, ligado ao carrier.
+// Retorna também a chave de arquivo (chaves derivadas) e, com -shares, as
+// partes geradas dela.
+func encryptPayload(data []byte, keys keyOptions, b crypto.Binding) ([]byte, *crypto.ArchiveKeys, []crypto.Share, error) {
+	recipients, err := keys.cryptoRecipients()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	data, archive, err := crypto.EncryptArchive(data, b, recipients...)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var shares []crypto.Share
+	for _, r := range recipients {
+		if sr, ok := r.(*crypto.ShareRecipient); ok {
+			shares = sr.Shares
+		}
+	}
+	return data, archive, shares, nil
+}
+
+// openArchive: Abre o header NCC3 do payload em path (nil se não for NCC3),
+// uma vez só: o MAC do índice Merkle e a decifragem usam a mesma chave
+func openArchive(path string, keys keyOptions) (*crypto.ArchiveKeys, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read output: %w", err)
+	}
+	defer f.Close()
+	return readArchive(bufio.NewReader(f), keys)
+}
+
+// readArchive: openArchive sobre o início do payload em r
+func readArchive(r *bufio.Reader, keys keyOptions) (*crypto.ArchiveKeys, error) {
+	if prefix, _ := r.Peek(len(crypto.StreamMagic)); !crypto.IsStream(prefix) {
+		return nil, nil
+	}
+	identities, err := keys.cryptoIdentities()
+	if err != nil {
+		return nil, err
+	}
+	if len(identities) == 0 {
+		return nil, fmt.Errorf("payload cifrado: informe uma senha (-ask-password, -password-env...), -identity ou -share")
+	}
+	archive, err := crypto.OpenArchiveKeys(r, identities...)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt: %w", err)
+	}
+	return archive, nil
+}
+
+// carrierBinding: Metadados do carrier autenticados pela cifra. Vídeo e
+// páginas ganham um ID novo (gravado no GlobalHeader); áudio e stego não
+// têm onde guardá-lo.
+func carrierBinding(outputPath, preset, redundancy, cover string, pages bool) (crypto.Binding, error) {
+	if cover != "" {
+		return crypto.Binding{Descriptor: descriptorStego}, nil
+	}
+	if audio.IsAudioFile(outputPath) && !pages {
+		return crypto.Binding{Descriptor: descriptorAudio}, nil
+	}
+	id, err := crypto.NewArchiveID()
+	if err != nil {
+		return crypto.Binding{}, err
+	}
+	cfg := encoder.PresetFrameConfig(preset)
+	return crypto.Binding{Descriptor: cfg.Descriptor(encoder.NewECCConfig(redundancy)), ArchiveID: id}, nil
+}
+
+// frameScrambler: Scrambler do -scramble para a grade de cfg (nil sem a
+// flag). A chave vem da chave de arquivo (aleatória): sem a senha, -identity
+// ou as partes não há como desembaralhar, nem testar senhas contra ela. Os
+// leadFrames frames iniciais (header NCC3) usam a chave pública.
+func (k keyOptions) frameScrambler(archive *crypto.ArchiveKeys, leadFrames int, cfg encoder.FrameConfig) (*encoder.Scrambler, error) {
+	if !k.scramble {
+		return nil, nil
+	}
+	if archive == nil {
+		return nil, fmt.Errorf("-scramble exige cifra (-ask-password, -password-env, -recipient ou -shares)")
+	}
+	key, err := archive.ScrambleKey()
+	if err != nil {
+		return nil, fmt.Errorf("scramble: %w", err)
+	}
+	return encoder.NewScrambler(key, leadFrames, cfg)
+}
+
+// decryptReader: Leitor do payload decifrado (encrypted = true) ou do
+// próprio payload sem cifra, só quando nenhuma opção de decifrar foi dada.
+// NCC2 legado só é tentado com senha. observed: carrier decodificado,
+// conferido com o binding autenticado. Com skip (-partial) segmentos NCC3
+// inválidos viram zeros e ficam no relatório.
+func decryptReader(r *bufio.Reader, keys keyOptions, observed crypto.Binding, skip *crypto.SkipReport) (io.Reader, bool, error) {
+	prefix, _ := r.Peek(len(crypto.StreamMagic))
+	if !crypto.IsStream(prefix) && !keys.decrypting() {
+		fmt.Println("Descomprimindo (sem senha)...")
+		return r, false, nil
+	}
+	// -identity/-share pedem NCC3: um payload em claro no lugar de um
+	// cifrado seria um rebaixamento silencioso
+	if !crypto.IsStream(prefix) && keys.password == "" {
+		return nil, false, fmt.Errorf("payload não cifrado, mas -identity/-share foi informado")
+	}
+	identities, err := keys.cryptoIdentities()
+	if err != nil {
+		return nil, false, err
+	}
+	if len(identities) == 0 {
+		return nil, false, fmt.Errorf("payload cifrado: informe uma senha (-ask-password, -password-env...), -identity ou -share")
+	}
+	fmt.Println("Decriptando...")
+	// SEGURANÇA: cada segmento é autenticado antes de ser descomprimido
+	if skip != nil {
+		dec, err := crypto.DecryptSkipping(r, observed, skip, identities...)
+		return dec, true, err
+	}
+	dec, err := crypto.Decrypt(r, observed, identities...)
+	return dec, true, err
+}
+
+// runCalibrate: Parâmetros do KDF para desbloquear em cerca de target
+// nesta máquina (memória dada ou padrão)
+func runCalibrate(target time.Duration, memoryMiB int) error {
+	memory := crypto.DefaultKDFParams().Memory
+	if memoryMiB > 0 {
+		var err error
+		if memory, err = crypto.KDFMemoryMiB(memoryMiB); err != nil {
+			return fmt.Errorf("-kdf-memory: %w", err)
+		}
+	}
+	fmt.Printf("Calibrando Argon2id para ~%s...\n", target)
+	params, took, err := crypto.CalibrateKDF(target, memory, crypto.DefaultKDFParams().Threads)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("🔑 %s (~%s por desbloqueio)\n", params, took.Round(time.Millisecond))
+	fmt.Printf("Use: -kdf-memory=%d -kdf-time=%d\n", params.Memory/1024, params.Time)
+	return nil
+}
+
+// runKeygen: Novo arquivo de identidade X25519 (chave secreta, 0600)
+func runKeygen(outputPath string) error {
+	id, err := crypto.GenerateX25519Identity()
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(outputPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("criar identidade (não sobrescreve): %w", err)
+	}
+	if err := crypto.WriteIdentityFile(f, id); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("🔑 Identidade salva: %s (mantenha em segredo)\n", outputPath)
+	fmt.Printf("Chave pública: %s\n", id.Recipient())
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDecodeRefusesPlainPayload(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.txt")
+	data := bytes.Repeat([]byte("ncc plain payload\n"), 200)
+	if err := os.WriteFile(input, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	video := filepath.Join(dir, "plain.nccv")
+	if err := runEncode(input, video, keyOptions{}, "low", 2, "default", "none", false, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	idPath := filepath.Join(dir, "id.txt")
+	if err := runKeygen(idPath); err != nil {
+		t.Fatal(err)
+	}
+
+	// Com -identity o payload precisa ser NCC3
+	out := filepath.Join(dir, "out.txt")
+	err := runDecode(video, out, keyOptions{identities: []string{idPath}}, "default", false, false, false, false)
+	if err == nil || !strings.Contains(err.Error(), "não cifrado") {
+		t.Fatalf("decode com -identity: err = %v, want payload não cifrado", err)
+	}
+
+	// Sem opção de decifrar, o mesmo vídeo abre normalmente
+	if err := runDecode(video, out, keyOptions{}, "default", false, false, false, false); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("decode sem cifra difere do original")
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ncc/internal/crypto"
+	"ncc/internal/encoder"
+	"ncc/internal/trailer"
+)
+
+func TestUnpackPayloadManifest(t *testing.T) {
+	data := make([]byte, 2*compressChunk+300)
+	rand.New(rand.NewSource(2)).Read(data)
+	payload, err := compressData(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	good := trailer.Manifest{Size: uint64(len(data)), SHA256: encoder.CalculateFileHash(data)}
+	badHash := good
+	badHash.SHA256[0] ^= 1
+	badSize := good
+	badSize.Size++
+
+	for _, tc := range []struct {
+		name     string
+		manifest trailer.Manifest
+		partial  bool
+		ok       bool
+	}{
+		{"confere", good, false, true},
+		{"SHA-256 diferente", badHash, false, false},
+		{"tamanho diferente", badSize, false, false},
+		{"confere (-partial)", good, true, true},
+		{"SHA-256 diferente (-partial)", badHash, true, false},
+	} {
+		path := filepath.Join(t.TempDir(), "out.bin")
+		if err := os.WriteFile(path, payload, 0644); err != nil {
+			t.Fatal(err)
+		}
+		m := tc.manifest
+		err := unpackPayload(path, keyOptions{}, crypto.Binding{}, &m, tc.partial)
+		if _, statErr := os.Stat(path + ".part"); statErr == nil {
+			t.Errorf("%s: temporário .part ficou no disco", tc.name)
+		}
+		if !tc.ok {
+			if err == nil || !strings.Contains(err.Error(), "INTEGRIDADE") {
+				t.Errorf("%s: err = %v, want erro de INTEGRIDADE", tc.name, err)
+			}
+			// O payload bruto não pode ficar com o nome do arquivo pedido
+			if _, statErr := os.Stat(path); statErr == nil {
+				t.Errorf("%s: payload bruto ficou em %s", tc.name, path)
+			}
+			if _, statErr := os.Stat(path + ".payload"); statErr != nil {
+				t.Errorf("%s: payload bruto não foi mantido em .payload: %v", tc.name, statErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("%s: arquivo final difere", tc.name)
+		}
+	}
+}
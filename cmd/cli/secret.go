@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// passwordSource: Origem da senha na linha de comando (no máximo uma)
+type passwordSource struct {
+	value string // -password (fica no histórico do shell e no ps)
+	ask   bool   // -ask-password: prompt sem eco
+	env   string // -password-env: nome da variável de ambiente
+	file  string // -password-file: primeira linha do arquivo
+	fd    int    // -password-fd: descritor herdado (-1 = não usado)
+}
+
+// resolvePassword: Senha da origem escolhida ("" se nenhuma). confirm pede
+// a senha duas vezes no prompt (encode: um erro de digitação seria fatal).
+func resolvePassword(src passwordSource, confirm bool) (string, error) {
+	var set []string
+	if src.value != "" {
+		set = append(set, "-password")
+	}
+	if src.ask {
+		set = append(set, "-ask-password")
+	}
+	if src.env != "" {
+		set = append(set, "-password-env")
+	}
+	if src.file != "" {
+		set = append(set, "-password-file")
+	}
+	if src.fd >= 0 {
+		set = append(set, "-password-fd")
+	}
+	if len(set) > 1 {
+		return "", fmt.Errorf("use apenas uma origem de senha (%s)", strings.Join(set, ", "))
+	}
+
+	var password string
+	var err error
+	switch {
+	case src.value != "":
+		fmt.Fprintln(os.Stderr, "⚠️  -password fica no histórico do shell e visível no ps; prefira -ask-password, -password-env, -password-file ou -password-fd")
+		password = src.value
+	case src.ask:
+		password, err = promptPassword(confirm)
+	case src.env != "":
+		var ok bool
+		if password, ok = os.LookupEnv(src.env); !ok {
+			return "", fmt.Errorf("-password-env: variável %s não definida", src.env)
+		}
+	case src.file != "":
+		var f *os.File
+		if f, err = os.Open(src.file); err != nil {
+			return "", fmt.Errorf("-password-file: %w", err)
+		}
+		password, err = readSecretLine(f)
+		f.Close()
+	case src.fd >= 0:
+		f := os.NewFile(uintptr(src.fd), "password-fd")
+		if f == nil {
+			return "", fmt.Errorf("-password-fd: descritor %d inválido", src.fd)
+		}
+		password, err = readSecretLine(f)
+		f.Close()
+	default:
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if password == "" {
+		return "", fmt.Errorf("senha vazia (%s)", set[0])
+	}
+	return password, nil
+}
+
+// promptPassword: Leitura sem eco do terminal (prompt em stderr)
+func promptPassword(confirm bool) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("-ask-password exige um terminal; use -password-env, -password-file ou -password-fd")
+	}
+	fmt.Fprint(os.Stderr, "🔑 Senha: ")
+	first, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("ler senha: %w", err)
+	}
+	if confirm {
+		fmt.Fprint(os.Stderr, "🔑 Confirme a senha: ")
+		second, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("ler senha: %w", err)
+		}
+		if string(first) != string(second) {
+			return "", fmt.Errorf("as senhas não conferem")
+		}
+	}
+	return string(first), nil
+}
+
+// readSecretLine: Primeira linha (sem \r\n final) de um arquivo ou pipe
+func readSecretLine(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("ler senha: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
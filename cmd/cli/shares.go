@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"ncc/internal/crypto"
+)
+
+// parseShareSpec: -shares "k-of-n" (ex.: 2-of-3)
+func parseShareSpec(spec string) (*crypto.ShareRecipient, error) {
+	ks, ns, ok := strings.Cut(spec, "-of-")
+	k, errK := strconv.Atoi(ks)
+	n, errN := strconv.Atoi(ns)
+	if !ok || errK != nil || errN != nil {
+		return nil, fmt.Errorf("-shares inválido: %q (use k-of-n, ex.: 2-of-3)", spec)
+	}
+	r, err := crypto.NewShareRecipient(k, n)
+	if err != nil {
+		return nil, fmt.Errorf("-shares: %w", err)
+	}
+	return r, nil
+}
+
+// loadShares: -share aceita a parte "NCC-SHARE-..." ou um arquivo com partes
+func loadShares(args []string) ([]crypto.Share, error) {
+	var out []crypto.Share
+	for _, arg := range args {
+		if strings.HasPrefix(arg, crypto.SharePrefix) {
+			sh, err := crypto.ParseShare(arg)
+			if err != nil {
+				return nil, fmt.Errorf("-share: %w", err)
+			}
+			out = append(out, sh)
+			continue
+		}
+		f, err := os.Open(arg)
+		if err != nil {
+			return nil, fmt.Errorf("-share: %w", err)
+		}
+		shs, err := crypto.ParseShareFile(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("-share %s: %w", arg, err)
+		}
+		out = append(out, shs...)
+	}
+	return out, nil
+}
+
+// writeShares: Uma parte por arquivo (0600) ao lado da saída,
+// "<saída>_shareN.txt"; com pages, também "<saída>_shareN.pdf" para
+// imprimir (recuperável com ncc scan). Sem partes, nada.
+func writeShares(outputPath string, shares []crypto.Share, pages bool) error {
+	if len(shares) == 0 {
+		return nil
+	}
+	outputPath = filepath.Clean(outputPath) // Diretório de PNGs: partes ao lado, não dentro
+	base := strings.TrimSuffix(outputPath, filepath.Ext(outputPath))
+	for _, sh := range shares {
+		path := fmt.Sprintf("%s_share%d.txt", base, sh.X)
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return fmt.Errorf("salvar parte: %w", err)
+		}
+		if err := crypto.WriteShareFile(f, sh, filepath.Base(outputPath)); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		fmt.Printf("🧩 Parte %d de %d salva: %s\n", sh.X, sh.N, path)
+
+		if pages {
+			pdf := fmt.Sprintf("%s_share%d.pdf", base, sh.X)
+			if err := runEncode(path, pdf, keyOptions{}, "high", 0, "paper", "none", true, "", ""); err != nil {
+				return fmt.Errorf("páginas da parte %d: %w", sh.X, err)
+			}
+			fmt.Printf("🖨️  Parte %d para impressão: %s\n", sh.X, pdf)
+		}
+	}
+	fmt.Printf("🧩 Quaisquer %d das %d partes decifram o arquivo (-share); entregue cada uma a uma pessoa\n",
+		shares[0].K, shares[0].N)
+	return nil
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"ncc/internal/crypto"
+	"ncc/internal/trailer"
+)
+
+// Descritores dos carriers sem frames (a assinatura cobre o carrier usado)
+const (
+	descriptorAudio = "audio"
+	descriptorStego = "stego"
+)
+
+// appendTrailer: Anexa ao payload (já cifrado) o trailer com os registros
+// dados, o índice Merkle (payloads de mais de um chunk) e, com -sign, a
+// assinatura Ed25519 da raiz (ou do hash) e da configuração do carrier.
+// A raiz é autenticada pelo MAC com a chave de arquivo (archive) ou, sem
+// senha, pelo prefixo retornado para o GlobalHeader do frame 0.
+func appendTrailer(data []byte, records []trailer.Record, keyPath, descriptor string, archive *crypto.ArchiveKeys) ([]byte, [8]byte, error) {
+	var index *trailer.MerkleIndex
+	var pin [8]byte
+	if len(data) > trailer.MerkleChunkSize(int64(len(data))) {
+		m := trailer.NewMerkleIndex(data)
+		index = &m
+		records = append(records, m.Record())
+		root := m.Root()
+		if archive != nil {
+			mac, err := archive.IndexMAC(root)
+			if err != nil {
+				return nil, pin, err
+			}
+			records = append(records, trailer.Record{Type: trailer.RecordMerkleMAC, Value: mac})
+		} else {
+			pin = [8]byte(root[:8])
+		}
+	}
+	if keyPath != "" {
+		f, err := os.Open(keyPath)
+		if err != nil {
+			return nil, pin, fmt.Errorf("-sign: %w", err)
+		}
+		key, err := crypto.ParseSigningKeyFile(f)
+		f.Close()
+		if err != nil {
+			return nil, pin, fmt.Errorf("-sign %s: %w", keyPath, err)
+		}
+		var sig crypto.Signature
+		if index != nil {
+			sig = crypto.SignMerkleRoot(key, index.Root(), descriptor)
+		} else {
+			sig = crypto.SignPayload(key, sha256.Sum256(data), descriptor)
+		}
+		fmt.Printf("✍️  Assinado por %s (%s)\n", sig.Key, descriptor)
+		records = append(records, trailer.Record{Type: trailer.RecordSignature, Value: sig.Encode()})
+	}
+	if len(records) == 0 {
+		return data, pin, nil
+	}
+	data, err := trailer.Append(data, records)
+	return data, pin, err
+}
+
+// indexAnchor: O que autentica o índice Merkle fora da assinatura: a chave
+// de arquivo (MAC no trailer) ou o prefixo da raiz lido do frame 0
+type indexAnchor struct {
+	archive *crypto.ArchiveKeys
+	pinned  [8]byte
+}
+
+// verify: A raiz do índice confere com o MAC (payload cifrado) ou com o
+// frame 0 (sem senha)
+func (a indexAnchor) verify(records []trailer.Record, root [32]byte) bool {
+	if a.archive != nil {
+		rec := trailer.Find(records, trailer.RecordMerkleMAC)
+		if rec == nil {
+			return false
+		}
+		mac, err := a.archive.IndexMAC(root)
+		return err == nil && hmac.Equal(mac, rec.Value)
+	}
+	return a.pinned != [8]byte{} && a.pinned == [8]byte(root[:8])
+}
+
+// loadVerifyKey: -verify aceita a chave "ncc-sig-..." ou um arquivo com ela
+func loadVerifyKey(arg string) (*crypto.VerifyKey, error) {
+	if arg == "" {
+		return nil, nil
+	}
+	if strings.HasPrefix(arg, crypto.VerifyKeyPrefix) {
+		return crypto.ParseVerifyKey(arg)
+	}
+	f, err := os.Open(arg)
+	if err != nil {
+		return nil, fmt.Errorf("-verify: %w", err)
+	}
+	defer f.Close()
+	key, err := crypto.ParseVerifyKeyFile(f)
+	if err != nil {
+		return nil, fmt.Errorf("-verify %s: %w", arg, err)
+	}
+	return key, nil
+}
+
+// verifyTrailer: Confere o índice Merkle e a assinatura do payload bruto em
+// path e remove o trailer, retornando seus registros e os intervalos do
+// payload que não conferem com o índice. Com want (-verify) a falta ou falha
+// da assinatura é fatal (chunks danificados só são aceitos com partial); sem
+// ele só avisa. descriptor = "" quando a configuração não é conhecida. Um
+// índice sem autenticação (anchor ou assinatura da chave do -verify) é
+// ignorado.
+func verifyTrailer(path, descriptor string, want *crypto.VerifyKey, partial bool, anchor indexAnchor) ([]trailer.Record, []trailer.Range, error) {
+	records, payloadSize, ok, err := trailer.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("trailer: %w", err)
+	}
+	if !ok {
+		if want != nil {
+			return nil, nil, fmt.Errorf("payload sem assinatura (-verify exige um arquivo gerado com -sign)")
+		}
+		return nil, nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read output: %w", err)
+	}
+	defer f.Close() // Fechado antes de remover o trailer
+
+	// Índice Merkle: cada chunk conferido por conta própria
+	var index *trailer.MerkleIndex
+	var bad []trailer.Range
+	if rec := trailer.Find(records, trailer.RecordMerkle); rec != nil {
+		m, err := trailer.DecodeMerkleIndex(rec.Value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("trailer: %w", err)
+		}
+		index = &m
+		if bad, err = m.Check(io.NewSectionReader(f, 0, payloadSize), payloadSize); err != nil {
+			return nil, nil, fmt.Errorf("índice Merkle: %w", err)
+		}
+	}
+	anchored := index != nil && anchor.verify(records, index.Root())
+
+	rec := trailer.Find(records, trailer.RecordSignature)
+	if rec == nil {
+		if want != nil {
+			return nil, nil, fmt.Errorf("payload sem assinatura (-verify exige um arquivo gerado com -sign)")
+		}
+		f.Close()
+		return records, unanchoredIndex(index, anchored, bad), trailer.StripFile(path, payloadSize)
+	}
+	sig, err := crypto.DecodeSignature(rec.Value)
+	if err != nil {
+		return nil, nil, fmt.Errorf("assinatura: %w", err)
+	}
+
+	// Com índice a assinatura cobre a raiz (vale mesmo com chunks perdidos);
+	// sem ele, o hash do payload inteiro
+	var valid, intact bool
+	if index != nil {
+		valid = sig.VerifyMerkleRoot(index.Root())
+		intact = len(bad) == 0
+	} else {
+		hash, err := crypto.HashPayload(io.NewSectionReader(f, 0, payloadSize))
+		if err != nil {
+			return nil, nil, fmt.Errorf("read output: %w", err)
+		}
+		valid = sig.Verify(hash)
+		intact = valid
+	}
+
+	layoutOK := descriptor == "" || sig.Descriptor == descriptor
+	if want != nil {
+		if !sig.Key.Equal(want) {
+			return nil, nil, fmt.Errorf("assinado por outra chave: %s (esperado %s)", sig.Key, want)
+		}
+		if !valid || (!intact && (index == nil || !partial)) {
+			return nil, nil, fmt.Errorf("assinatura inválida: payload alterado ou corrompido")
+		}
+		if !layoutOK {
+			return nil, nil, fmt.Errorf("configuração assinada (%s) difere da decodificada (%s)", sig.Descriptor, descriptor)
+		}
+		if intact {
+			fmt.Printf("✅ Assinatura verificada: %s\n", sig.Key)
+		} else {
+			fmt.Printf("✅ Assinatura verificada: %s (só os chunks íntegros)\n", sig.Key)
+		}
+	} else if !valid || !intact {
+		fmt.Fprintf(os.Stderr, "⚠️  WARNING: assinatura inválida (payload alterado?) de %s\n", sig.Key)
+	} else {
+		if !layoutOK {
+			fmt.Fprintf(os.Stderr, "⚠️  WARNING: configuração assinada (%s) difere da decodificada (%s)\n", sig.Descriptor, descriptor)
+		}
+		fmt.Printf("ℹ️  Assinado por %s (use -verify para exigir)\n", sig.Key)
+	}
+	// Só a chave do -verify autentica o índice: sem ela qualquer um assina
+	// um índice forjado com a própria chave
+	signed := index != nil && valid && want != nil && sig.Key.Equal(want)
+	f.Close()
+	return records, unanchoredIndex(index, anchored || signed, bad), trailer.StripFile(path, payloadSize)
+}
+
+// unanchoredIndex: Intervalos ruins do índice, ou nenhum (com aviso) se a
+// raiz não foi autenticada: o trailer fica fora da cifra e um índice forjado
+// descartaria chunks íntegros
+func unanchoredIndex(index *trailer.MerkleIndex, anchored bool, bad []trailer.Range) []trailer.Range {
+	if index == nil || anchored {
+		return bad
+	}
+	fmt.Fprintln(os.Stderr, "⚠️  Índice Merkle sem autenticação (MAC, frame 0 ou assinatura): ignorado")
+	return nil
+}
+
+// runSignKeygen: Nova chave de assinatura Ed25519 (0600)
+func runSignKeygen(outputPath string) error {
+	key, err := crypto.GenerateSigningKey()
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(outputPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("criar chave (não sobrescreve): %w", err)
+	}
+	if err := crypto.WriteSigningKeyFile(f, key); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("✍️  Chave de assinatura salva: %s (mantenha em segredo)\n", outputPath)
+	fmt.Printf("Chave de verificação: %s\n", key.Public())
+	return nil
+}
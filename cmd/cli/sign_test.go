@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"ncc/internal/crypto"
+	"ncc/internal/trailer"
+)
+
+func TestIndexAnchor(t *testing.T) {
+	id, err := crypto.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := bytes.Repeat([]byte("ncc"), 10000)
+
+	// Cifrado: MAC da raiz com a chave de arquivo
+	_, archive, err := crypto.EncryptArchive(payload, crypto.Binding{}, id.Recipient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, pin, err := appendTrailer(payload, nil, "", "", archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pin != [8]byte{} {
+		t.Error("payload cifrado fixou a raiz no frame 0")
+	}
+	rest, records, ok, err := trailer.Split(data)
+	if err != nil || !ok {
+		t.Fatalf("trailer: %v", err)
+	}
+	rec := trailer.Find(records, trailer.RecordMerkle)
+	if rec == nil {
+		t.Fatal("sem índice Merkle")
+	}
+	index, err := trailer.DecodeMerkleIndex(rec.Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := index.Root()
+	if !bytes.Equal(rest, payload) || !(indexAnchor{archive: archive}).verify(records, root) {
+		t.Error("MAC da raiz não confere")
+	}
+	forged := root
+	forged[0] ^= 1
+	if (indexAnchor{archive: archive}).verify(records, forged) {
+		t.Error("raiz forjada aceita")
+	}
+	_, other, err := crypto.EncryptArchive(payload, crypto.Binding{}, id.Recipient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (indexAnchor{archive: other}).verify(records, root) {
+		t.Error("MAC de outra chave de arquivo aceito")
+	}
+
+	// Sem senha: prefixo da raiz no frame 0
+	if _, pin, err = appendTrailer(payload, nil, "", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if !(indexAnchor{pinned: pin}).verify(nil, root) {
+		t.Error("raiz fixada no frame 0 não confere")
+	}
+	if (indexAnchor{pinned: pin}).verify(nil, forged) || (indexAnchor{}).verify(nil, root) {
+		t.Error("índice sem âncora aceito")
+	}
+}
+
+func TestVerifyTrailerSignedIndex(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "sign.key")
+	if err := runSignKeygen(keyPath); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := crypto.ParseSigningKeyFile(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Índice forjado: calculado sobre outro payload e assinado pela chave
+	// do atacante; o payload real fica intacto
+	payload := bytes.Repeat([]byte("ncc"), 20000)
+	forged := append([]byte(nil), payload...)
+	forged[10000] ^= 1
+	signed, _, err := appendTrailer(forged, nil, keyPath, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := append(append([]byte(nil), payload...), signed[len(forged):]...)
+
+	for _, tc := range []struct {
+		name string
+		want *crypto.VerifyKey
+		bad  bool
+	}{
+		{"sem -verify", nil, false},
+		{"-verify da chave", key.Public(), true},
+	} {
+		path := filepath.Join(dir, "payload.bin")
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, bad, err := verifyTrailer(path, "", tc.want, true, indexAnchor{})
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if (len(bad) > 0) != tc.bad {
+			t.Errorf("%s: intervalos ruins = %v", tc.name, bad)
+		}
+	}
+}
@@ -6,6 +6,7 @@ require (
 	github.com/klauspost/compress v1.18.4
 	github.com/klauspost/reedsolomon v1.13.2
 	golang.org/x/crypto v0.48.0
+	golang.org/x/term v0.40.0
 )
 
 require (
@@ -0,0 +1,103 @@
+package audio
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// chunkSymbols: Símbolos modulados por vez na escrita em streaming
+const chunkSymbols = 1024
+
+// IsAudioFile: Arquivo de áudio puro (carrier sem vídeo), pela extensão
+func IsAudioFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".wav", ".flac", ".opus", ".ogg", ".oga", ".m4a", ".aac", ".mp3":
+		return true
+	}
+	return false
+}
+
+// EncodeFile: Payload -> arquivo de áudio (copies cópias do pacote). WAV é
+// Go puro; demais formatos são transcodificados pelo FFmpeg a partir de um
+// WAV temporário.
+func EncodeFile(outputPath string, data []byte, copies int) error {
+	if strings.EqualFold(filepath.Ext(outputPath), ".wav") {
+		return writeCarrierWAV(outputPath, data, copies)
+	}
+
+	tmp, err := os.CreateTemp("", "ncc-*.wav")
+	if err != nil {
+		return fmt.Errorf("create temp: %w", err)
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+	defer os.Remove(tmpPath)
+
+	if err := writeCarrierWAV(tmpPath, data, copies); err != nil {
+		return err
+	}
+
+	// Codec e bitrate padrão do FFmpeg para a extensão (FLAC, Opus, AAC, MP3)
+	cmd := exec.Command(findFFmpeg(), "-y", "-v", "error", "-i", tmpPath, "-ac", "1", outputPath)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg transcode: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// writeCarrierWAV: WAV do pacote em streaming (memória constante mesmo para
+// horas de áudio)
+func writeCarrierWAV(path string, data []byte, copies int) error {
+	p := Packet{Kind: KindPayload, Data: data}
+	symbols, err := p.symbols()
+	if err != nil {
+		return err
+	}
+	copies = max(1, copies)
+	lead := int(leadSeconds * SampleRate)
+	total := lead + copies*len(symbols)*int(symbolLength(SampleRate))
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", path, err)
+	}
+	w := bufio.NewWriterSize(f, 1024*1024)
+	err = writeWAVHeader(w, total, SampleRate)
+	if err == nil {
+		err = writePCM(w, make([]float64, lead))
+	}
+	for c := 0; c < copies && err == nil; c++ {
+		for i := 0; i < len(symbols) && err == nil; i += chunkSymbols {
+			err = writePCM(w, modulate(symbols[i:min(i+chunkSymbols, len(symbols))]))
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// DecodeFile: Payload de um arquivo gerado por EncodeFile, inclusive
+// re-encodado com perdas
+func DecodeFile(inputPath string) ([]byte, error) {
+	samples, rate, err := ReadTrack(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	pkt, err := Decode(samples, rate)
+	if err != nil {
+		return nil, err
+	}
+	if pkt.Kind != KindPayload {
+		return nil, fmt.Errorf("faixa de áudio leva um %s, não o payload (use o vídeo original)", KindName(pkt.Kind))
+	}
+	return pkt.Data, nil
+}
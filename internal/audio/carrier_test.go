@@ -0,0 +1,24 @@
+package audio
+
+import (
+	"bytes"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+func TestCarrierWAVRoundTrip(t *testing.T) {
+	data := make([]byte, 700)
+	rand.New(rand.NewSource(3)).Read(data)
+	path := filepath.Join(t.TempDir(), "carrier.wav")
+	if err := EncodeFile(path, data, 2); err != nil {
+		t.Fatal(err)
+	}
+	got, err := DecodeFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("payload difere: %d bytes, want %d", len(got), len(data))
+	}
+}
@@ -0,0 +1,196 @@
+// Package audio implementa um modem acústico MFSK para levar dados em uma
+// faixa de áudio (junto do vídeo ou sozinha). Cada símbolo de 20 ms toca
+// um tom em cada um de 4 grupos de 16 frequências (16 bits/símbolo); tons
+// ortogonais na janela do símbolo e banda de 1 a 7.3 kHz sobrevivem a
+// AAC/Opus nas taxas usuais das plataformas.
+package audio
+
+import "math"
+
+// Parâmetros do modem
+const (
+	SampleRate    = 48000
+	SymbolSeconds = 0.02
+	ToneGroups    = 4
+	TonesPerGroup = 16
+	BitsPerSymbol = 16 // ToneGroups x log2(TonesPerGroup)
+
+	baseFreq    = 1000.0 // Hz
+	toneSpacing = 100.0  // Hz (múltiplo de 1/SymbolSeconds: tons ortogonais)
+	toneLevel   = 0.18   // Amplitude de cada tom (4 simultâneos < 1)
+	rampSeconds = 0.002  // Rampa cosseno nas bordas do símbolo (sem cliques)
+
+	syncThreshold = 0.45 // Fração mínima da energia nos tons do preâmbulo
+)
+
+// preamble: Símbolos de sincronismo no início de cada cópia do pacote
+var preamble = [...]uint16{
+	0x52E6, 0xF2A7, 0x269E, 0x6513, 0xA6A3, 0x0C5C, 0x128B, 0xD23F,
+	0x892F, 0x1818, 0x5D9D, 0x9531, 0x0ED9, 0xE8E2, 0x81E7, 0x36F6,
+}
+
+// toneFreq: Frequência do tom t do grupo g. Grupos intercalados: um corte
+// de banda do codec atinge todos os grupos igualmente
+func toneFreq(g, t int) float64 {
+	return baseFreq + float64(t*ToneGroups+g)*toneSpacing
+}
+
+// symbolNibble: Valor (tom) do grupo g no símbolo
+func symbolNibble(sym uint16, g int) int {
+	return int(sym>>(12-4*g)) & 0xF
+}
+
+// symbolLength: Amostras por símbolo na taxa dada
+func symbolLength(rate int) float64 {
+	return SymbolSeconds * float64(rate)
+}
+
+// modulate: Símbolos -> amostras em SampleRate
+func modulate(symbols []uint16) []float64 {
+	n := int(symbolLength(SampleRate))
+	ramp := int(rampSeconds * SampleRate)
+	window := make([]float64, n)
+	for i := range window {
+		window[i] = 1
+		if i < ramp {
+			window[i] = 0.5 - 0.5*math.Cos(math.Pi*float64(i)/float64(ramp))
+		} else if i >= n-ramp {
+			window[i] = 0.5 - 0.5*math.Cos(math.Pi*float64(n-1-i)/float64(ramp))
+		}
+	}
+
+	out := make([]float64, len(symbols)*n)
+	for k, sym := range symbols {
+		buf := out[k*n : (k+1)*n]
+		for g := 0; g < ToneGroups; g++ {
+			w := 2 * math.Pi * toneFreq(g, symbolNibble(sym, g)) / SampleRate
+			for i := range buf {
+				buf[i] += toneLevel * window[i] * math.Sin(w*float64(i))
+			}
+		}
+	}
+	return out
+}
+
+// demodulator: Leitura de símbolos em amostras de qualquer taxa
+type demodulator struct {
+	samples []float64
+	rate    int
+	symLen  float64
+	coeffs  [ToneGroups][TonesPerGroup]float64 // Goertzel: 2cos(w)
+	energy  []float64                          // Soma acumulada de x² (normalização)
+}
+
+func newDemodulator(samples []float64, rate int) *demodulator {
+	d := &demodulator{samples: samples, rate: rate, symLen: symbolLength(rate)}
+	for g := 0; g < ToneGroups; g++ {
+		for t := 0; t < TonesPerGroup; t++ {
+			d.coeffs[g][t] = 2 * math.Cos(2*math.Pi*toneFreq(g, t)/float64(rate))
+		}
+	}
+	d.energy = make([]float64, len(samples)+1)
+	for i, x := range samples {
+		d.energy[i+1] = d.energy[i] + x*x
+	}
+	return d
+}
+
+// window: Amostras do símbolo k a partir de start (nil se fora do áudio)
+func (d *demodulator) window(start float64, k int) []float64 {
+	a := int(math.Round(start + float64(k)*d.symLen))
+	b := a + int(math.Round(d.symLen))
+	if a < 0 || b > len(d.samples) {
+		return nil
+	}
+	return d.samples[a:b]
+}
+
+// goertzel: Energia de uma frequência (coeficiente 2cos(w)) na janela
+func goertzel(x []float64, coeff float64) float64 {
+	var s1, s2 float64
+	for _, v := range x {
+		s1, s2 = v+coeff*s1-s2, s1
+	}
+	return s1*s1 + s2*s2 - coeff*s1*s2
+}
+
+// symbol: Tom mais forte de cada grupo; ok = false fora do áudio
+func (d *demodulator) symbol(start float64, k int) (uint16, bool) {
+	x := d.window(start, k)
+	if x == nil {
+		return 0, false
+	}
+	var sym uint16
+	for g := 0; g < ToneGroups; g++ {
+		best, bestE := 0, -1.0
+		for t := 0; t < TonesPerGroup; t++ {
+			if e := goertzel(x, d.coeffs[g][t]); e > bestE {
+				best, bestE = t, e
+			}
+		}
+		sym = sym<<4 | uint16(best)
+	}
+	return sym, true
+}
+
+// syncScore: Fração da energia nos tons esperados do preâmbulo em start
+// (1 = sinal limpo alinhado, ~0 = ruído ou desalinhado)
+func (d *demodulator) syncScore(start float64) float64 {
+	return d.preambleScore(start, len(preamble))
+}
+
+// preambleScore: syncScore só dos n primeiros símbolos do preâmbulo
+func (d *demodulator) preambleScore(start float64, n int) float64 {
+	var tones, total float64
+	for k, sym := range preamble[:n] {
+		x := d.window(start, k)
+		if x == nil {
+			return 0
+		}
+		for g := 0; g < ToneGroups; g++ {
+			tones += goertzel(x, d.coeffs[g][symbolNibble(sym, g)])
+		}
+		a := int(math.Round(start + float64(k)*d.symLen))
+		total += d.energy[a+len(x)] - d.energy[a]
+	}
+	if total == 0 {
+		return 0
+	}
+	// Tom puro de N amostras: Goertzel = (aN/2)², energia = a²N/2
+	return tones / (total * d.symLen / 2)
+}
+
+// findPreamble: Primeiro preâmbulo a partir de from (busca grossa a cada
+// 1/4 de símbolo, refinada até 1 amostra). ok = false se não houver.
+func (d *demodulator) findPreamble(from float64) (float64, bool) {
+	span := float64(len(preamble)) * d.symLen
+	step := d.symLen / 4
+	for start := math.Max(0, from); start+span <= float64(len(d.samples)); start += step {
+		// Pré-filtro barato (2 símbolos) antes do score completo
+		if d.preambleScore(start, 2) < syncThreshold/2 || d.syncScore(start) < syncThreshold/2 {
+			continue
+		}
+		best := d.refine(start, step)
+		if d.syncScore(best) >= syncThreshold {
+			return best, true
+		}
+	}
+	return 0, false
+}
+
+// refine: Máximo local do score em [center-reach, center+reach]
+func (d *demodulator) refine(center, reach float64) float64 {
+	best, bestScore := center, d.syncScore(center)
+	for step := reach / 8; ; step /= 4 {
+		c := best
+		for off := -reach; off <= reach; off += step {
+			if s := d.syncScore(c + off); s > bestScore {
+				best, bestScore = c+off, s
+			}
+		}
+		if step <= 1 {
+			return best
+		}
+		reach = step
+	}
+}
@@ -0,0 +1,275 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"math"
+
+	"github.com/klauspost/reedsolomon"
+)
+
+// Tipos de pacote
+const (
+	KindManifest = 1 // Cópia do frame 0 (FrameHeader + GlobalHeader + dados)
+	KindPayload  = 2 // Cópia completa do payload
+)
+
+// Estrutura do pacote
+const (
+	headerSize    = 12 // Magic "NA" | Versão | Tipo | Tamanho u32 | CRC32
+	headerRepeats = 3
+	headerVersion = 1
+
+	dataShards   = 16
+	parityShards = 16 // Metade dos shards pode se perder (por cópia)
+	maxShardSize = 64
+	shardCRCSize = 4
+
+	leadSeconds = 0.2 // Silêncio inicial (atraso/priming do codec)
+)
+
+var headerMagic = [2]byte{'N', 'A'}
+
+// Packet: Dados levados pela faixa de áudio
+type Packet struct {
+	Kind byte
+	Data []byte
+}
+
+// KindName: Nome do tipo (logs)
+func KindName(kind byte) string {
+	switch kind {
+	case KindManifest:
+		return "manifest"
+	case KindPayload:
+		return "payload"
+	}
+	return fmt.Sprintf("tipo %d", kind)
+}
+
+// layout: Shards por grupo Reed-Solomon para n bytes
+func layout(n int) (shardSize, groups int) {
+	shardSize = min(maxShardSize, max(1, (n+dataShards-1)/dataShards))
+	groups = max(1, (n+dataShards*shardSize-1)/(dataShards*shardSize))
+	return shardSize, groups
+}
+
+// codedSize: Bytes codificados (grupos x shards x (dados + CRC)), par
+func codedSize(n int) int {
+	shardSize, groups := layout(n)
+	size := groups * (dataShards + parityShards) * (shardSize + shardCRCSize)
+	return size + size%2
+}
+
+// cycleSymbols: Símbolos de uma cópia (preâmbulo + headers + dados)
+func cycleSymbols(n int) int {
+	return len(preamble) + headerRepeats*headerSize/2 + codedSize(n)/2
+}
+
+// symbols: Uma cópia do pacote em símbolos de 16 bits
+func (p Packet) symbols() ([]uint16, error) {
+	header := make([]byte, headerSize)
+	copy(header[0:2], headerMagic[:])
+	header[2] = headerVersion
+	header[3] = p.Kind
+	binary.BigEndian.PutUint32(header[4:8], uint32(len(p.Data)))
+	binary.BigEndian.PutUint32(header[8:12], crc32.ChecksumIEEE(header[:8]))
+
+	coded, err := encodeData(p.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	out := append([]uint16(nil), preamble[:]...)
+	for i := 0; i < headerRepeats; i++ {
+		out = appendSymbols(out, header)
+	}
+	return appendSymbols(out, coded), nil
+}
+
+func appendSymbols(out []uint16, b []byte) []uint16 {
+	for i := 0; i+1 < len(b); i += 2 {
+		out = append(out, binary.BigEndian.Uint16(b[i:]))
+	}
+	return out
+}
+
+// encodeData: Grupos RS 16+16; cada shard leva CRC32 para virar apagamento
+// quando corrompido
+func encodeData(data []byte) ([]byte, error) {
+	shardSize, groups := layout(len(data))
+	enc, err := reedsolomon.New(dataShards, parityShards)
+	if err != nil {
+		return nil, fmt.Errorf("create RS encoder: %w", err)
+	}
+
+	out := make([]byte, 0, codedSize(len(data)))
+	for g := 0; g < groups; g++ {
+		shards := make([][]byte, dataShards+parityShards)
+		for i := range shards {
+			shards[i] = make([]byte, shardSize)
+			if i < dataShards {
+				if start := (g*dataShards + i) * shardSize; start < len(data) {
+					copy(shards[i], data[start:])
+				}
+			}
+		}
+		if err := enc.Encode(shards); err != nil {
+			return nil, fmt.Errorf("RS encode: %w", err)
+		}
+		for _, s := range shards {
+			out = append(out, s...)
+			out = binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(s))
+		}
+	}
+	if len(out)%2 == 1 {
+		out = append(out, 0) // Símbolos de 16 bits
+	}
+	return out, nil
+}
+
+// Render: Áudio mono em SampleRate com cópias do pacote até cobrir
+// minSeconds (ao menos uma cópia)
+func Render(p Packet, minSeconds float64) ([]float64, int, error) {
+	symbols, err := p.symbols()
+	if err != nil {
+		return nil, 0, err
+	}
+	cycle := float64(len(symbols)) * SymbolSeconds
+	copies := max(1, int(math.Ceil((minSeconds-leadSeconds)/cycle)))
+
+	all := make([]uint16, 0, copies*len(symbols))
+	for i := 0; i < copies; i++ {
+		all = append(all, symbols...)
+	}
+	lead := make([]float64, int(leadSeconds*SampleRate))
+	return append(lead, modulate(all)...), copies, nil
+}
+
+// Duration: Segundos de uma cópia de n bytes
+func Duration(n int) float64 {
+	return float64(cycleSymbols(n)) * SymbolSeconds
+}
+
+// Decode: Localiza as cópias do pacote no áudio (qualquer taxa, mono) e
+// combina os shards íntegros de todas elas
+func Decode(samples []float64, rate int) (Packet, error) {
+	d := newDemodulator(samples, rate)
+	start, ok := d.findPreamble(0)
+	if !ok {
+		return Packet{}, fmt.Errorf("nenhum sinal NCC na faixa de áudio")
+	}
+
+	// Header: primeira cópia íntegra, desta ou das próximas cópias. O
+	// tamanho só tem CRC: precisa caber no áudio lido (uma cópia inteira),
+	// senão um header forjado ou ruidoso pediria uma alocação arbitrária
+	var kind byte
+	var length int
+	maxSymbols := float64(len(samples)) / d.symLen
+	for {
+		if kind, length, ok = d.header(start); ok && float64(cycleSymbols(length)) <= maxSymbols {
+			break
+		}
+		if start, ok = d.findPreamble(start + d.symLen); !ok {
+			return Packet{}, fmt.Errorf("header da faixa de áudio ilegível")
+		}
+	}
+
+	// Cópias antes e depois da encontrada (período conhecido), cada uma
+	// realinhada localmente
+	period := float64(cycleSymbols(length)) * d.symLen
+	for start-period >= 0 {
+		start -= period
+	}
+	var coded [][]byte
+	for s := start; s < float64(len(samples)); s += period {
+		if aligned := d.refine(s, d.symLen/4); d.syncScore(aligned) >= syncThreshold {
+			s = aligned
+		}
+		if c, ok := d.copyData(s, length); ok {
+			coded = append(coded, c)
+		}
+	}
+
+	data, err := decodeData(coded, length)
+	if err != nil {
+		return Packet{}, err
+	}
+	fmt.Printf("🔊 Faixa de áudio: %s de %d bytes (%d cópias lidas)\n", KindName(kind), length, len(coded))
+	return Packet{Kind: kind, Data: data}, nil
+}
+
+// header: Tipo e tamanho pela primeira das cópias do header com CRC válido
+func (d *demodulator) header(start float64) (byte, int, bool) {
+	base := len(preamble)
+	for r := 0; r < headerRepeats; r++ {
+		buf := make([]byte, 0, headerSize)
+		for k := 0; k < headerSize/2; k++ {
+			sym, ok := d.symbol(start, base+r*headerSize/2+k)
+			if !ok {
+				return 0, 0, false
+			}
+			buf = binary.BigEndian.AppendUint16(buf, sym)
+		}
+		if buf[0] != headerMagic[0] || buf[1] != headerMagic[1] || buf[2] != headerVersion {
+			continue
+		}
+		if crc32.ChecksumIEEE(buf[:8]) != binary.BigEndian.Uint32(buf[8:12]) {
+			continue
+		}
+		return buf[3], int(binary.BigEndian.Uint32(buf[4:8])), true
+	}
+	return 0, 0, false
+}
+
+// copyData: Bytes codificados de uma cópia (ok = false se truncada)
+func (d *demodulator) copyData(start float64, length int) ([]byte, bool) {
+	base := len(preamble) + headerRepeats*headerSize/2
+	n := codedSize(length) / 2
+	out := make([]byte, 0, 2*n)
+	for k := 0; k < n; k++ {
+		sym, ok := d.symbol(start, base+k)
+		if !ok {
+			return nil, false
+		}
+		out = binary.BigEndian.AppendUint16(out, sym)
+	}
+	return out, true
+}
+
+// decodeData: Para cada shard, a primeira cópia com CRC válido; shards sem
+// cópia íntegra viram apagamentos do Reed-Solomon
+func decodeData(copies [][]byte, n int) ([]byte, error) {
+	if len(copies) == 0 {
+		return nil, fmt.Errorf("nenhuma cópia completa do pacote na faixa de áudio")
+	}
+	shardSize, groups := layout(n)
+	enc, err := reedsolomon.New(dataShards, parityShards)
+	if err != nil {
+		return nil, fmt.Errorf("create RS decoder: %w", err)
+	}
+
+	wire := shardSize + shardCRCSize
+	out := make([]byte, 0, groups*dataShards*shardSize)
+	for g := 0; g < groups; g++ {
+		shards := make([][]byte, dataShards+parityShards)
+		for i := range shards {
+			off := (g*len(shards) + i) * wire
+			for _, c := range copies {
+				w := c[off : off+wire]
+				if crc32.ChecksumIEEE(w[:shardSize]) == binary.BigEndian.Uint32(w[shardSize:]) {
+					shards[i] = w[:shardSize]
+					break
+				}
+			}
+		}
+		if err := enc.ReconstructData(shards); err != nil {
+			return nil, fmt.Errorf("grupo %d irrecuperável na faixa de áudio: %w", g, err)
+		}
+		for _, s := range shards[:dataShards] {
+			out = append(out, s...)
+		}
+	}
+	return out[:n], nil
+}
@@ -0,0 +1,106 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPacketRoundTrip(t *testing.T) {
+	data := make([]byte, 1500)
+	rand.New(rand.NewSource(1)).Read(data)
+	p := Packet{Kind: KindManifest, Data: data}
+	samples, copies, err := Render(p, leadSeconds+2*Duration(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if copies != 2 {
+		t.Fatalf("%d cópias, want 2", copies)
+	}
+
+	// Trechos diferentes destruídos em cada cópia: nenhuma cópia sozinha é
+	// íntegra, os shards bons das duas se completam
+	lead := int(leadSeconds * SampleRate)
+	cycle := cycleSymbols(len(data)) * int(symbolLength(SampleRate))
+	dataStart := (len(preamble) + headerRepeats*headerSize/2) * int(symbolLength(SampleRate))
+	dataLen := cycle - dataStart
+	clear(samples[lead+dataStart : lead+dataStart+dataLen/3])
+	second := lead + cycle + dataStart
+	clear(samples[second+2*dataLen/3 : second+dataLen])
+
+	got, err := Decode(samples, SampleRate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Kind != p.Kind || !bytes.Equal(got.Data, data) {
+		t.Errorf("pacote difere: tipo %d, %d bytes", got.Kind, len(got.Data))
+	}
+}
+
+func TestDecodeDataErasures(t *testing.T) {
+	data := make([]byte, 3000) // Mais de um grupo RS
+	rand.New(rand.NewSource(2)).Read(data)
+	coded, err := encodeData(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shardSize, _ := layout(len(data))
+	wire := shardSize + shardCRCSize
+	corrupt := func(b []byte, shards ...int) []byte {
+		out := append([]byte(nil), b...)
+		for _, i := range shards {
+			out[i*wire] ^= 0xFF // CRC do shard falha: apagamento
+		}
+		return out
+	}
+
+	// Até parityShards shards perdidos por grupo
+	var lost []int
+	for i := 0; i < parityShards; i++ {
+		lost = append(lost, 2*i)
+	}
+	got, err := decodeData([][]byte{corrupt(coded, lost...)}, len(data))
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("%d apagamentos: err = %v", len(lost), err)
+	}
+
+	// Um a mais é irrecuperável com uma cópia, mas outra cópia repõe o shard
+	lost = append(lost, 1)
+	if _, err := decodeData([][]byte{corrupt(coded, lost...)}, len(data)); err == nil {
+		t.Fatal("grupo com apagamentos demais aceito")
+	}
+	got, err = decodeData([][]byte{corrupt(coded, lost...), corrupt(coded, 1)}, len(data))
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("shards combinados de duas cópias: err = %v", err)
+	}
+}
+
+// TestDecodeRejectsOversizedHeader: Tamanho do header (só CRC) maior que o
+// áudio lido é recusado antes de qualquer alocação
+func TestDecodeRejectsOversizedHeader(t *testing.T) {
+	header := make([]byte, headerSize)
+	copy(header[0:2], headerMagic[:])
+	header[2] = headerVersion
+	header[3] = KindPayload
+	binary.BigEndian.PutUint32(header[4:8], 1<<31)
+	binary.BigEndian.PutUint32(header[8:12], crc32.ChecksumIEEE(header[:8]))
+
+	symbols := append([]uint16(nil), preamble[:]...)
+	for i := 0; i < headerRepeats; i++ {
+		symbols = appendSymbols(symbols, header)
+	}
+	symbols = appendSymbols(symbols, make([]byte, 64))
+	samples := append(make([]float64, int(leadSeconds*SampleRate)), modulate(symbols)...)
+
+	start := time.Now()
+	if _, err := Decode(samples, SampleRate); err == nil || !strings.Contains(err.Error(), "header") {
+		t.Fatalf("err = %v, want header recusado", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("rejeição levou %v", d)
+	}
+}
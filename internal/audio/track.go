@@ -0,0 +1,94 @@
+package audio
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// SidecarPath: .wav ao lado de uma saída sem faixa de áudio (Y4M, NCCV,
+// sequência PNG): "backup.y4m" -> "backup.wav", "frames/" -> "frames.wav"
+func SidecarPath(outputPath string) string {
+	base := strings.TrimRight(outputPath, `/\`)
+	return strings.TrimSuffix(base, filepath.Ext(base)) + ".wav"
+}
+
+// ReadTrack: Áudio mono da entrada. WAV é lido direto; vídeos Go puro e
+// diretórios usam o .wav ao lado; demais formatos passam pelo FFmpeg.
+func ReadTrack(path string) ([]float64, int, error) {
+	if strings.EqualFold(filepath.Ext(path), ".wav") {
+		return readWAVFile(path)
+	}
+	if info, err := os.Stat(path); err != nil || info.IsDir() || isRawVideo(path) {
+		sidecar := SidecarPath(path)
+		if _, err := os.Stat(sidecar); err != nil {
+			return nil, 0, fmt.Errorf("sem faixa de áudio (%s não encontrado)", sidecar)
+		}
+		return readWAVFile(sidecar)
+	}
+
+	cmd := exec.Command(findFFmpeg(),
+		"-v", "error",
+		"-i", path,
+		"-vn", "-ac", "1", "-ar", fmt.Sprint(SampleRate),
+		"-f", "wav", "pipe:1",
+	)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, 0, fmt.Errorf("start ffmpeg: %w", err)
+	}
+	samples, rate, readErr := ReadWAV(stdout)
+	if err := cmd.Wait(); err != nil {
+		return nil, 0, fmt.Errorf("extrair áudio (sem faixa de áudio?): %w", err)
+	}
+	if readErr != nil {
+		return nil, 0, readErr
+	}
+	return samples, rate, nil
+}
+
+// isRawVideo: Containers Go puro do NCC (sem áudio)
+func isRawVideo(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".y4m", ".nccv":
+		return true
+	}
+	return false
+}
+
+func readWAVFile(path string) ([]float64, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("open %s: %w", path, err)
+	}
+	defer f.Close()
+	return ReadWAV(f)
+}
+
+// findFFmpeg: Busca FFmpeg no PATH e locais comuns
+func findFFmpeg() string {
+	if path, err := exec.LookPath("ffmpeg"); err == nil {
+		return path
+	}
+
+	locations := []string{
+		`C:\ffmpeg\bin\ffmpeg.exe`,
+		`C:\Program Files\ffmpeg\bin\ffmpeg.exe`,
+		`C:\Program Files (x86)\ffmpeg\bin\ffmpeg.exe`,
+		filepath.Join(os.Getenv("LOCALAPPDATA"), "Microsoft", "WinGet", "Links", "ffmpeg.exe"),
+		filepath.Join(os.Getenv("USERPROFILE"), "scoop", "shims", "ffmpeg.exe"),
+	}
+
+	for _, loc := range locations {
+		if _, err := os.Stat(loc); err == nil {
+			return loc
+		}
+	}
+
+	return "ffmpeg"
+}
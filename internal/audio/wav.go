@@ -0,0 +1,179 @@
+package audio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// Formatos do chunk fmt
+const (
+	wavPCM        = 1
+	wavFloat      = 3
+	wavExtensible = 0xFFFE
+)
+
+// WriteWAV: PCM 16 bits mono
+func WriteWAV(w io.Writer, samples []float64, rate int) error {
+	bw := bufio.NewWriter(w)
+	if err := writeWAVHeader(bw, len(samples), rate); err != nil {
+		return err
+	}
+	if err := writePCM(bw, samples); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// writeWAVHeader: Header PCM 16 bits mono para n amostras
+func writeWAVHeader(w io.Writer, n, rate int) error {
+	dataSize := uint32(2 * n)
+
+	var hdr [44]byte
+	copy(hdr[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(hdr[4:8], 36+dataSize)
+	copy(hdr[8:12], "WAVE")
+	copy(hdr[12:16], "fmt ")
+	binary.LittleEndian.PutUint32(hdr[16:20], 16)
+	binary.LittleEndian.PutUint16(hdr[20:22], wavPCM)
+	binary.LittleEndian.PutUint16(hdr[22:24], 1) // Mono
+	binary.LittleEndian.PutUint32(hdr[24:28], uint32(rate))
+	binary.LittleEndian.PutUint32(hdr[28:32], uint32(rate*2))
+	binary.LittleEndian.PutUint16(hdr[32:34], 2)
+	binary.LittleEndian.PutUint16(hdr[34:36], 16)
+	copy(hdr[36:40], "data")
+	binary.LittleEndian.PutUint32(hdr[40:44], dataSize)
+	_, err := w.Write(hdr[:])
+	return err
+}
+
+// writePCM: Amostras -1..1 em PCM 16 bits little-endian
+func writePCM(w io.Writer, samples []float64) error {
+	var buf [2]byte
+	for _, s := range samples {
+		v := math.Round(math.Max(-1, math.Min(1, s)) * 32767)
+		binary.LittleEndian.PutUint16(buf[:], uint16(int16(v)))
+		if _, err := w.Write(buf[:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteWAVFile: WriteWAV em um arquivo
+func WriteWAVFile(path string, samples []float64, rate int) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", path, err)
+	}
+	if err := WriteWAV(f, samples, rate); err != nil {
+		f.Close()
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// ReadWAV: Amostras (canais somados em mono, -1..1) e taxa. Aceita PCM
+// 8/16/24/32 bits e float 32/64, inclusive o WAV em pipe do FFmpeg (tamanho
+// do chunk de dados desconhecido: lê até o fim).
+func ReadWAV(r io.Reader) ([]float64, int, error) {
+	br := bufio.NewReader(r)
+	var riff [12]byte
+	if _, err := io.ReadFull(br, riff[:]); err != nil {
+		return nil, 0, fmt.Errorf("read wav header: %w", err)
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return nil, 0, fmt.Errorf("not a WAV file")
+	}
+
+	var format, channels, bits int
+	var rate int
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(br, chunk[:]); err != nil {
+			return nil, 0, fmt.Errorf("wav sem chunk de dados: %w", err)
+		}
+		id := string(chunk[0:4])
+		size := binary.LittleEndian.Uint32(chunk[4:8])
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return nil, 0, fmt.Errorf("wav fmt chunk too small: %d bytes", size)
+			}
+			body := make([]byte, size+size%2)
+			if _, err := io.ReadFull(br, body); err != nil {
+				return nil, 0, fmt.Errorf("read wav fmt chunk: %w", err)
+			}
+			format = int(binary.LittleEndian.Uint16(body[0:2]))
+			channels = int(binary.LittleEndian.Uint16(body[2:4]))
+			rate = int(binary.LittleEndian.Uint32(body[4:8]))
+			bits = int(binary.LittleEndian.Uint16(body[14:16]))
+			if format == wavExtensible && size >= 26 {
+				format = int(binary.LittleEndian.Uint16(body[24:26])) // Subformato (GUID)
+			}
+		case "data":
+			if channels == 0 || rate == 0 {
+				return nil, 0, fmt.Errorf("wav: chunk de dados antes do fmt")
+			}
+			var data io.Reader = br
+			if size != 0 && size != math.MaxUint32 {
+				data = io.LimitReader(br, int64(size))
+			}
+			samples, err := readSamples(data, format, channels, bits)
+			return samples, rate, err
+		default:
+			if _, err := br.Discard(int(size + size%2)); err != nil {
+				return nil, 0, fmt.Errorf("skip wav chunk %q: %w", id, err)
+			}
+		}
+	}
+}
+
+// readSamples: Quadros intercalados -> mono
+func readSamples(r io.Reader, format, channels, bits int) ([]float64, error) {
+	width := bits / 8
+	if width == 0 || (format != wavPCM && format != wavFloat) ||
+		(format == wavFloat && width != 4 && width != 8) || (format == wavPCM && width > 4) {
+		return nil, fmt.Errorf("wav: formato %d com %d bits não suportado", format, bits)
+	}
+
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read wav data: %w", err)
+	}
+	frame := width * channels
+	out := make([]float64, len(raw)/frame)
+	for i := range out {
+		var sum float64
+		for c := 0; c < channels; c++ {
+			b := raw[i*frame+c*width:]
+			sum += sampleValue(b[:width], format)
+		}
+		out[i] = sum / float64(channels)
+	}
+	return out, nil
+}
+
+// sampleValue: Amostra little-endian normalizada para -1..1
+func sampleValue(b []byte, format int) float64 {
+	if format == wavFloat {
+		if len(b) == 8 {
+			return math.Float64frombits(binary.LittleEndian.Uint64(b))
+		}
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	}
+	if len(b) == 1 {
+		return (float64(b[0]) - 128) / 128 // 8 bits é sem sinal
+	}
+	var v int64
+	for i := len(b) - 1; i >= 0; i-- {
+		v = v<<8 | int64(b[i])
+	}
+	shift := 64 - 8*len(b)
+	v = v << shift >> shift // Extensão de sinal
+	return float64(v) / float64(int64(1)<<(8*len(b)-1))
+}
@@ -1,75 +1,90 @@
-package cluster
-
-import (
-	"bytes"
-	"encoding/gob"
-	"encoding/json"
-	"fmt"
-)
-
-// JobConfig: Parâmetros de encode enviados ao conectar
-type JobConfig struct {
-	// Configuração de Frame
-	Width             int `json:"width"`
-	Height            int `json:"height"`
-	MacroSize         int `json:"macroSize"`
-	FPS               int `json:"fps"`
-	CalibrationHeight int `json:"calibrationHeight"`
-	GrayLevels        int `json:"grayLevels"`
-
-	// Configuração ECC
-	DataShards   int `json:"dataShards"`
-	ParityShards int `json:"parityShards"`
-
-	// Metadados do Arquivo
-	TotalFrames  int      `json:"totalFrames"`
-	OriginalSize uint64   `json:"originalSize"`
-	FileHash     [32]byte `json:"fileHash"`
-}
-
-// FrameJob: Frame individual para processamento
-type FrameJob struct {
-	FrameIndex int
-	Data       []byte // Chunk bruto de dados
-}
-
-// FrameResult: Resultado do processamento
-type FrameResult struct {
-	FrameIndex       int
-	CompressedPixels []byte // Pixels RGBA (zstd)
-	Width            int
-	Height           int
-	Error            string // Vazio se OK
-}
-
-// WorkerInfo: Capacidades do worker
-type WorkerInfo struct {
-	Hostname string `json:"hostname"`
-	CPUCores int    `json:"cpuCores"`
-	OS       string `json:"os"`
-	Arch     string `json:"arch"`
-}
-
-// ---- Encoding GOB (Binário eficiente) ----
-
-func EncodeGob(v interface{}) ([]byte, error) {
-	var buf bytes.Buffer
-	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
-		return nil, fmt.Errorf("gob encode: %w", err)
-	}
-	return buf.Bytes(), nil
-}
-
-func DecodeGob(data []byte, v interface{}) error {
-	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
-}
-
-// ---- Encoding JSON (Legível) ----
-
-func EncodeJSON(v interface{}) ([]byte, error) {
-	return json.Marshal(v)
-}
-
-func DecodeJSON(data []byte, v interface{}) error {
-	return json.Unmarshal(data, v)
-}
+package cluster
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+)
+
+// ProtocolVersion: Versão do protocolo master/worker (1 = sem versão, antigo),
+// enviada pelo worker em ProtocolHeader em toda requisição
+const (
+	ProtocolVersion = 2
+	ProtocolHeader  = "X-NCC-Protocol"
+)
+
+// JobConfig: Parâmetros de encode enviados ao conectar
+type JobConfig struct {
+	Version int `json:"version"` // ProtocolVersion do master
+
+	// Configuração de Frame
+	Width             int    `json:"width"`
+	Height            int    `json:"height"`
+	MacroSize         int    `json:"macroSize"`
+	FPS               int    `json:"fps"`
+	CalibrationHeight int    `json:"calibrationHeight"`
+	GrayLevels        int    `json:"grayLevels"`
+	Modulation        string `json:"modulation,omitempty"`
+
+	// Configuração ECC
+	DataShards   int `json:"dataShards"`
+	ParityShards int `json:"parityShards"`
+
+	// Metadados do Arquivo
+	TotalFrames  int      `json:"totalFrames"`
+	OriginalSize uint64   `json:"originalSize"`
+	FileHash     [32]byte `json:"fileHash"`
+	ArchiveID    [6]byte  `json:"archiveId"` // GlobalHeader (frame 0)
+	Volume       uint16   `json:"volume,omitempty"`
+	IndexRoot    [8]byte  `json:"indexRoot"`              // GlobalHeader: prefixo da raiz Merkle
+	ScrambleKey  []byte   `json:"scrambleKey,omitempty"`  // -scramble (HKDF da chave de arquivo, não a abre)
+	ScrambleLead int      `json:"scrambleLead,omitempty"` // Frames do header NCC3 (chave pública)
+}
+
+// FrameJob: Frame individual para processamento
+type FrameJob struct {
+	FrameIndex int
+	Data       []byte // Chunk bruto de dados
+}
+
+// FrameResult: Resultado do processamento
+type FrameResult struct {
+	FrameIndex       int
+	CompressedPixels []byte // Pixels cinza, 1 byte/pixel (zstd)
+	Width            int
+	Height           int
+	Error            string // Vazio se OK
+}
+
+// WorkerInfo: Capacidades do worker
+type WorkerInfo struct {
+	Hostname string `json:"hostname"`
+	CPUCores int    `json:"cpuCores"`
+	OS       string `json:"os"`
+	Arch     string `json:"arch"`
+}
+
+// ---- Encoding GOB (Binário eficiente) ----
+
+func EncodeGob(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, fmt.Errorf("gob encode: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func DecodeGob(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// ---- Encoding JSON (Legível) ----
+
+func EncodeJSON(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func DecodeJSON(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
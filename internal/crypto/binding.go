@@ -0,0 +1,95 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Binding: Metadados do carrier ligados ao payload (NCC3 v2). Vão em claro
+// no header, entram no MAC do header e são os dados associados (AAD) de cada
+// segmento: header do vídeo alterado ou frames de outro arquivo falham na
+// decifragem.
+type Binding struct {
+	Descriptor string  // Layout dos frames (FrameConfig + ECC), "audio" ou "stego"
+	ArchiveID  [6]byte // ID aleatório do arquivo (GlobalHeader do frame 0; zero sem frames)
+	Volume     uint16  // Volume do arquivo (0 = único)
+
+	// HasArchive: Só no binding observado (não serializado): o carrier
+	// informou ArchiveID/Volume. Áudio e stego informam (sempre zero); sem o
+	// frame 0 o vídeo não informa.
+	HasArchive bool
+}
+
+// ErrBinding: Metadados do carrier não conferem com os autenticados
+var ErrBinding = errors.New("payload não pertence a este carrier")
+
+const maxDescriptor = 255
+
+// NewArchiveID: ID aleatório para um novo arquivo
+func NewArchiveID() ([6]byte, error) {
+	var id [6]byte
+	_, err := io.ReadFull(rand.Reader, id[:])
+	return id, err
+}
+
+// encode: ID (6) | Volume u16 | Tamanho do descritor u8 | Descritor
+func (b Binding) encode() ([]byte, error) {
+	if len(b.Descriptor) > maxDescriptor {
+		return nil, fmt.Errorf("descriptor too long: %d bytes", len(b.Descriptor))
+	}
+	out := append([]byte(nil), b.ArchiveID[:]...)
+	out = binary.BigEndian.AppendUint16(out, b.Volume)
+	out = append(out, byte(len(b.Descriptor)))
+	return append(out, b.Descriptor...), nil
+}
+
+// readBinding: Inverso de encode; retorna também os bytes lidos (para o MAC)
+func readBinding(r io.Reader) (Binding, []byte, error) {
+	var b Binding
+	fixed := make([]byte, 9)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return b, nil, fmt.Errorf("read NCC3 binding: %w", err)
+	}
+	copy(b.ArchiveID[:], fixed[:6])
+	b.Volume = binary.BigEndian.Uint16(fixed[6:8])
+	desc := make([]byte, fixed[8])
+	if _, err := io.ReadFull(r, desc); err != nil {
+		return b, nil, fmt.Errorf("read NCC3 binding: %w", err)
+	}
+	b.Descriptor = string(desc)
+	return b, append(fixed, desc...), nil
+}
+
+// aad: Dados associados dos segmentos: versão do formato + binding
+func (b Binding) aad() ([]byte, error) {
+	enc, err := b.encode()
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{'N', 'C', 'C', '3', streamVersion}, enc...), nil
+}
+
+// Check: Confere o binding autenticado com o observado no carrier. Cada
+// campo é comparado quando o carrier o informa: descritor vazio = layout
+// desconhecido (ex.: payload da faixa de áudio), sem HasArchive o ID e o
+// volume ficam de fora.
+func (b Binding) Check(observed Binding) error {
+	if observed.Descriptor != "" && observed.Descriptor != b.Descriptor {
+		return fmt.Errorf("%w: cifrado para %q, decodificado como %q (header alterado?)",
+			ErrBinding, b.Descriptor, observed.Descriptor)
+	}
+	if !observed.HasArchive {
+		return nil
+	}
+	if observed.ArchiveID != b.ArchiveID {
+		return fmt.Errorf("%w: arquivo %x, frame 0 diz %x (frames de outro arquivo?)",
+			ErrBinding, b.ArchiveID, observed.ArchiveID)
+	}
+	if observed.Volume != b.Volume {
+		return fmt.Errorf("%w: volume %d, frame 0 diz %d", ErrBinding, b.Volume, observed.Volume)
+	}
+	return nil
+}
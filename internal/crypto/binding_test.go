@@ -0,0 +1,33 @@
+package crypto
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBindingCheck(t *testing.T) {
+	b := Binding{Descriptor: "16x16 g2 p48", ArchiveID: [6]byte{1, 2, 3, 4, 5, 6}, Volume: 2}
+	other := [6]byte{6, 5, 4, 3, 2, 1}
+
+	for _, tc := range []struct {
+		name     string
+		observed Binding
+		ok       bool
+	}{
+		{"igual", Binding{Descriptor: b.Descriptor, ArchiveID: b.ArchiveID, Volume: 2, HasArchive: true}, true},
+		{"carrier desconhecido", Binding{}, true},
+		{"descritor diferente", Binding{Descriptor: "audio", HasArchive: true}, false},
+		{"ID sem descritor", Binding{ArchiveID: other, Volume: 2, HasArchive: true}, false},
+		{"ID diferente", Binding{Descriptor: b.Descriptor, ArchiveID: other, Volume: 2, HasArchive: true}, false},
+		{"volume diferente", Binding{Descriptor: b.Descriptor, ArchiveID: b.ArchiveID, Volume: 3, HasArchive: true}, false},
+		{"ID não informado", Binding{Descriptor: b.Descriptor}, true},
+	} {
+		err := b.Check(tc.observed)
+		if tc.ok && err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+		if !tc.ok && !errors.Is(err, ErrBinding) {
+			t.Errorf("%s: err = %v, want ErrBinding", tc.name, err)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package crypto
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Formato NCC3 (cifra em segmentos, estilo age/STREAM):
+// Magic "NCC3" | Versão u8 | Stanzas u8 | Stanza... | Binding | Nonce (16) | MAC (32) | Segmentos
+// Stanza: Tipo u8 | Tamanho u16 | Corpo. Cada stanza embrulha a mesma chave
+// de arquivo (aleatória) para um destinatário; o MAC (HMAC-SHA256 com chave
+// derivada da chave de arquivo) autentica o header inteiro.
+const (
+	streamVersion   = 1
+	fileKeySize     = 32
+	streamNonceSize = 16
+	headerMACSize   = 32
+	maxStanzas      = 255
+)
+
+// StreamMagic: Assinatura do formato NCC3 (em claro, início do payload)
+var StreamMagic = [4]byte{'N', 'C', 'C', '3'}
+
+// Tipos de stanza
+const (
+	StanzaPassword = 1 // Argon2id(senha, salt) embrulha a chave de arquivo
+	// StanzaX25519 = 2 (x25519.go), StanzaShares = 3 (shamir.go)
+)
+
+// Stanza: Chave de arquivo embrulhada para um destinatário
+type Stanza struct {
+	Type byte
+	Body []byte
+}
+
+// Recipient: Embrulha a chave de arquivo no encode
+type Recipient interface {
+	Wrap(fileKey []byte) (Stanza, error)
+}
+
+// Identity: Desembrulha a chave de arquivo no decode. Retorna
+// ErrNotRecipient para stanzas de outro tipo/destinatário.
+type Identity interface {
+	Unwrap(s Stanza) ([]byte, error)
+}
+
+// ErrNotRecipient: Stanza não pertence à identidade
+var ErrNotRecipient = errors.New("stanza not for this identity")
+
+// errDecrypt: Erro genérico (evita side-channels)
+var errDecrypt = errors.New("failed to decrypt: invalid password or corrupted data")
+
+// IsStream: Payload no formato NCC3
+func IsStream(prefix []byte) bool {
+	return len(prefix) >= len(StreamMagic) && bytes.Equal(prefix[:len(StreamMagic)], StreamMagic[:])
+}
+
+// streamHeader: Header NCC3 decodificado
+type streamHeader struct {
+	stanzas []Stanza
+	binding Binding
+	nonce   []byte
+	mac     []byte
+	raw     []byte // Bytes autenticados pelo MAC (magic até o nonce)
+}
+
+// newHeader: Chave de arquivo embrulhada para cada destinatário
+func newHeader(fileKey []byte, recipients []Recipient, b Binding) (*streamHeader, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("no recipients")
+	}
+	if len(recipients) > maxStanzas {
+		return nil, fmt.Errorf("too many recipients: %d", len(recipients))
+	}
+	h := &streamHeader{binding: b, nonce: make([]byte, streamNonceSize)}
+	if _, err := io.ReadFull(rand.Reader, h.nonce); err != nil {
+		return nil, err
+	}
+	for _, r := range recipients {
+		s, err := r.Wrap(fileKey)
+		if err != nil {
+			return nil, err
+		}
+		if len(s.Body) > 0xFFFF {
+			return nil, fmt.Errorf("stanza too large: %d bytes", len(s.Body))
+		}
+		h.stanzas = append(h.stanzas, s)
+	}
+	if err := h.checkShares(); err != nil {
+		return nil, err
+	}
+
+	buf := append([]byte(nil), StreamMagic[:]...)
+	buf = append(buf, streamVersion, byte(len(h.stanzas)))
+	for _, s := range h.stanzas {
+		buf = append(buf, s.Type)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(len(s.Body)))
+		buf = append(buf, s.Body...)
+	}
+	binding, err := b.encode()
+	if err != nil {
+		return nil, err
+	}
+	buf = append(buf, binding...)
+	h.raw = append(buf, h.nonce...)
+	mac, err := headerMAC(fileKey, h.raw)
+	if err != nil {
+		return nil, err
+	}
+	h.mac = mac
+	return h, nil
+}
+
+// aad: Dados associados dos segmentos
+func (h *streamHeader) aad() ([]byte, error) {
+	return h.binding.aad()
+}
+
+// bytes: Header serializado (com MAC)
+func (h *streamHeader) bytes() []byte {
+	return append(append([]byte(nil), h.raw...), h.mac...)
+}
+
+// readHeader: Lê o header NCC3 do início de r
+func readHeader(r *bufio.Reader) (*streamHeader, error) {
+	fixed := make([]byte, len(StreamMagic)+2)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return nil, fmt.Errorf("read NCC3 header: %w", err)
+	}
+	if !IsStream(fixed) {
+		return nil, errors.New("not an NCC3 payload")
+	}
+	if fixed[4] != streamVersion {
+		return nil, fmt.Errorf("unsupported NCC3 version %d", fixed[4])
+	}
+
+	h := &streamHeader{raw: fixed}
+	for i := 0; i < int(fixed[5]); i++ {
+		var sh [3]byte
+		if _, err := io.ReadFull(r, sh[:]); err != nil {
+			return nil, fmt.Errorf("read stanza: %w", err)
+		}
+		body := make([]byte, binary.BigEndian.Uint16(sh[1:]))
+		if _, err := io.ReadFull(r, body); err != nil {
+			return nil, fmt.Errorf("read stanza: %w", err)
+		}
+		h.stanzas = append(h.stanzas, Stanza{Type: sh[0], Body: body})
+		h.raw = append(append(h.raw, sh[:]...), body...)
+	}
+	b, raw, err := readBinding(r)
+	if err != nil {
+		return nil, err
+	}
+	h.binding = b
+	h.raw = append(h.raw, raw...)
+
+	tail := make([]byte, streamNonceSize+headerMACSize)
+	if _, err := io.ReadFull(r, tail); err != nil {
+		return nil, fmt.Errorf("read NCC3 header: %w", err)
+	}
+	h.nonce = tail[:streamNonceSize]
+	h.mac = tail[streamNonceSize:]
+	h.raw = append(h.raw, h.nonce...)
+	return h, nil
+}
+
+// unwrap: Chave de arquivo pela primeira identidade que abre uma stanza e
+// confere o MAC do header. Faltando partes (Shamir), o erro diz quantas.
+func (h *streamHeader) unwrap(identities []Identity) ([]byte, error) {
+	if err := h.checkShares(); err != nil {
+		return nil, err
+	}
+	if err := h.checkPasswords(); err != nil {
+		return nil, err
+	}
+	var sharesErr error
+	for _, id := range identities {
+		for _, s := range h.stanzas {
+			fileKey, err := id.Unwrap(s)
+			if errors.Is(err, ErrShares) {
+				sharesErr = err
+			}
+			if err != nil {
+				continue // Outro destinatário ou chave errada: próxima stanza
+			}
+			mac, err := headerMAC(fileKey, h.raw)
+			if err != nil {
+				return nil, err
+			}
+			if !hmac.Equal(mac, h.mac) {
+				return nil, errDecrypt
+			}
+			return fileKey, nil
+		}
+	}
+	if sharesErr != nil {
+		return nil, sharesErr
+	}
+	return nil, errDecrypt
+}
+
+// checkShares: Stanza de partes só sozinha: qualquer outra stanza abriria
+// o arquivo sem as k partes
+func (h *streamHeader) checkShares() error {
+	for _, s := range h.stanzas {
+		if s.Type == StanzaShares && len(h.stanzas) > 1 {
+			return ErrMixedShares
+		}
+	}
+	return nil
+}
+
+// checkPasswords: No máximo uma stanza de senha: cada uma custa um KDF
+// inteiro por senha tentada, antes do MAC
+func (h *streamHeader) checkPasswords() error {
+	n := 0
+	for _, s := range h.stanzas {
+		if s.Type == StanzaPassword {
+			n++
+		}
+	}
+	if n > 1 {
+		return fmt.Errorf("header com %d stanzas de senha (máx. 1)", n)
+	}
+	return nil
+}
+
+// headerMAC: HMAC-SHA256 do header com chave derivada da chave de arquivo
+func headerMAC(fileKey, raw []byte) ([]byte, error) {
+	key, err := hkdf.Key(sha256.New, fileKey, nil, "ncc3 header", 32)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(raw)
+	return mac.Sum(nil), nil
+}
+
+// payloadKey: Chave dos segmentos (chave de arquivo + nonce do header)
+func payloadKey(fileKey, nonce []byte) ([]byte, error) {
+	return hkdf.Key(sha256.New, fileKey, nonce, "ncc3 payload", 32)
+}
+
+// ArchiveKeys: Chave de arquivo já aberta, para as chaves derivadas usadas
+// fora da cifra e para decifrar sem repetir o KDF das stanzas
+type ArchiveKeys struct {
+	fileKey    []byte
+	headerSize int
+}
+
+// OpenArchiveKeys: Abre o header NCC3 no início de r (MAC conferido)
+func OpenArchiveKeys(r io.Reader, identities ...Identity) (*ArchiveKeys, error) {
+	h, err := readHeader(bufio.NewReader(r))
+	if err != nil {
+		return nil, err
+	}
+	fileKey, err := h.unwrap(identities)
+	if err != nil {
+		return nil, err
+	}
+	return &ArchiveKeys{fileKey: fileKey, headerSize: len(h.bytes())}, nil
+}
+
+// HeaderComplete: prefix já contém o header NCC3 inteiro (ou não é NCC3)
+func HeaderComplete(prefix []byte) bool {
+	_, err := readHeader(bufio.NewReader(bytes.NewReader(prefix)))
+	return !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// HeaderSize: Bytes do header NCC3 no início do payload
+func (k *ArchiveKeys) HeaderSize() int {
+	return k.headerSize
+}
+
+// ScrambleKey: Chave do -scramble. Vem da chave de arquivo (aleatória),
+// então não serve de oráculo para a senha.
+func (k *ArchiveKeys) ScrambleKey() ([]byte, error) {
+	return hkdf.Key(sha256.New, k.fileKey, nil, "ncc-scramble", 32)
+}
+
+// IndexMAC: HMAC-SHA256 da raiz do índice Merkle do payload cifrado. Só
+// quem abre o header calcula, então o índice do trailer fica autenticado.
+func (k *ArchiveKeys) IndexMAC(root [32]byte) ([]byte, error) {
+	key, err := hkdf.Key(sha256.New, k.fileKey, nil, "ncc3 merkle", 32)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(root[:])
+	return mac.Sum(nil), nil
+}
+
+// Identity: Identidade que entrega a chave já aberta (o MAC do header
+// continua conferido no decode)
+func (k *ArchiveKeys) Identity() Identity {
+	return archiveIdentity{k.fileKey}
+}
+
+type archiveIdentity struct {
+	fileKey []byte
+}
+
+func (i archiveIdentity) Unwrap(Stanza) ([]byte, error) {
+	return i.fileKey, nil
+}
@@ -0,0 +1,132 @@
+package crypto
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Algoritmos de derivação da senha
+const (
+	KDFArgon2id = 1
+)
+
+// kdfParamsSize: Algoritmo u8 | Iterações u32 | Memória (KiB) u32 | Threads u8
+const kdfParamsSize = 10
+
+// Limites aceitos no encode e na leitura. Os custos vêm do header sem
+// autenticação (o MAC só é conferido depois do KDF): um header malicioso não
+// pode pedir mais que maxKDFCost por senha tentada.
+const (
+	maxKDFTime   = 64
+	maxKDFMemory = 1024 * 1024 // 1 GiB em KiB
+	minKDFMemory = 8 * 1024    // 8 MiB
+	// maxKDFCost: Iterações x memória (KiB), 16x o padrão (6 x 128 MiB)
+	maxKDFCost = 16 * 6 * 128 * 1024
+)
+
+// KDFParams: Algoritmo e custos da derivação da chave pela senha,
+// gravados na stanza de senha ao lado do salt
+type KDFParams struct {
+	Algorithm byte
+	Time      uint32 // Iterações
+	Memory    uint32 // KiB
+	Threads   uint8
+}
+
+// DefaultKDFParams: Custos do NCC2 (6 iterações, 128 MiB, 4 threads)
+func DefaultKDFParams() KDFParams {
+	return KDFParams{Algorithm: KDFArgon2id, Time: 6, Memory: 128 * 1024, Threads: 4}
+}
+
+// String: Resumo legível ("Argon2id t=6 m=128 MiB p=4")
+func (p KDFParams) String() string {
+	return fmt.Sprintf("Argon2id t=%d m=%d MiB p=%d", p.Time, p.Memory/1024, p.Threads)
+}
+
+// Validate: Parâmetros dentro dos limites suportados
+func (p KDFParams) Validate() error {
+	if p.Algorithm != KDFArgon2id {
+		return fmt.Errorf("KDF desconhecido: %d", p.Algorithm)
+	}
+	if p.Time < 1 || p.Time > maxKDFTime {
+		return fmt.Errorf("iterações do KDF fora de 1..%d: %d", maxKDFTime, p.Time)
+	}
+	if p.Memory < minKDFMemory || p.Memory > maxKDFMemory {
+		return fmt.Errorf("memória do KDF fora de %d..%d MiB: %d MiB", minKDFMemory/1024, maxKDFMemory/1024, p.Memory/1024)
+	}
+	if cost := uint64(p.Time) * uint64(p.Memory); cost > maxKDFCost {
+		return fmt.Errorf("custo do KDF acima do limite: %d iterações x %d MiB (máx. %d)", p.Time, p.Memory/1024, maxKDFCost/1024)
+	}
+	if p.Threads < 1 {
+		return errors.New("threads do KDF deve ser >= 1")
+	}
+	return nil
+}
+
+// KDFMemoryMiB: Memória em MiB (flag) -> KiB. Conferida como int antes da
+// conversão: uint32(MiB)*1024 daria a volta e passaria no Validate.
+func KDFMemoryMiB(mib int) (uint32, error) {
+	if mib < minKDFMemory/1024 || mib > maxKDFMemory/1024 {
+		return 0, fmt.Errorf("memória do KDF fora de %d..%d MiB: %d MiB", minKDFMemory/1024, maxKDFMemory/1024, mib)
+	}
+	return uint32(mib) * 1024, nil
+}
+
+// KDFTime: Iterações (flag), conferidas antes da conversão para uint32
+func KDFTime(t int) (uint32, error) {
+	if t < 1 || t > maxKDFTime {
+		return 0, fmt.Errorf("iterações do KDF fora de 1..%d: %d", maxKDFTime, t)
+	}
+	return uint32(t), nil
+}
+
+// derive: Chave de 32 bytes a partir da senha e do salt
+func (p KDFParams) derive(password string, salt []byte) []byte {
+	return argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, 32)
+}
+
+func (p KDFParams) encode() []byte {
+	buf := []byte{p.Algorithm}
+	buf = binary.BigEndian.AppendUint32(buf, p.Time)
+	buf = binary.BigEndian.AppendUint32(buf, p.Memory)
+	return append(buf, p.Threads)
+}
+
+func decodeKDFParams(b []byte) (KDFParams, error) {
+	if len(b) < kdfParamsSize {
+		return KDFParams{}, errDecrypt
+	}
+	p := KDFParams{
+		Algorithm: b[0],
+		Time:      binary.BigEndian.Uint32(b[1:5]),
+		Memory:    binary.BigEndian.Uint32(b[5:9]),
+		Threads:   b[9],
+	}
+	return p, p.Validate()
+}
+
+// CalibrateKDF: Iterações para que a derivação leve cerca de target com a
+// memória e threads dadas. Se uma iteração já passa do alvo, a memória cai
+// pela metade (até o mínimo). Retorna também o tempo medido.
+func CalibrateKDF(target time.Duration, memoryKiB uint32, threads uint8) (KDFParams, time.Duration, error) {
+	p := KDFParams{Algorithm: KDFArgon2id, Time: 1, Memory: memoryKiB, Threads: threads}
+	if err := p.Validate(); err != nil {
+		return p, 0, err
+	}
+	salt := make([]byte, passwordSaltSize)
+	for {
+		start := time.Now()
+		p.derive("calibrate", salt)
+		perIter := time.Since(start)
+		if perIter <= target || p.Memory/2 < minKDFMemory {
+			limit := min(maxKDFTime, maxKDFCost/int(p.Memory))
+			p.Time = uint32(max(1, min(limit, int(target/max(perIter, time.Millisecond)))))
+			return p, time.Duration(p.Time) * perIter, nil
+		}
+		p.Memory /= 2
+	}
+}
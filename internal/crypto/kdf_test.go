@@ -0,0 +1,65 @@
+package crypto
+
+import (
+	"testing"
+	"time"
+)
+
+// passwordStanza: Stanza de senha com parâmetros arbitrários (sem derivar)
+func passwordStanza(p KDFParams) Stanza {
+	body := append(p.encode(), make([]byte, passwordSaltSize+passwordWrappedSize)...)
+	return Stanza{Type: StanzaPassword, Body: body}
+}
+
+func TestKDFParamsOutOfRange(t *testing.T) {
+	def := DefaultKDFParams()
+	for _, tc := range []struct {
+		name string
+		p    KDFParams
+	}{
+		{"memória 4 GiB", KDFParams{Algorithm: KDFArgon2id, Time: 1, Memory: 4 * 1024 * 1024, Threads: 4}},
+		{"1000 iterações", KDFParams{Algorithm: KDFArgon2id, Time: 1000, Memory: minKDFMemory, Threads: 4}},
+		{"custo acima do limite", KDFParams{Algorithm: KDFArgon2id, Time: maxKDFTime, Memory: maxKDFMemory, Threads: 4}},
+		{"memória abaixo do mínimo", KDFParams{Algorithm: KDFArgon2id, Time: 1, Memory: 1024, Threads: 4}},
+		{"zero iterações", KDFParams{Algorithm: KDFArgon2id, Time: 0, Memory: def.Memory, Threads: 4}},
+		{"algoritmo desconhecido", KDFParams{Algorithm: 9, Time: def.Time, Memory: def.Memory, Threads: 4}},
+	} {
+		if tc.p.Validate() == nil {
+			t.Errorf("%s: Validate aceitou %s", tc.name, tc.p)
+		}
+		// Recusado antes do KDF: nada de alocar 4 GiB por senha tentada
+		start := time.Now()
+		if _, err := NewPasswordIdentity("x").Unwrap(passwordStanza(tc.p)); err == nil {
+			t.Errorf("%s: stanza aceita", tc.name)
+		}
+		if took := time.Since(start); took > time.Second {
+			t.Errorf("%s: recusa levou %s (KDF executado?)", tc.name, took)
+		}
+	}
+	if err := def.Validate(); err != nil {
+		t.Errorf("padrão recusado: %v", err)
+	}
+}
+
+func TestHeaderRejectsExtraPasswordStanzas(t *testing.T) {
+	h := &streamHeader{binding: testBinding, nonce: make([]byte, streamNonceSize)}
+	p := DefaultKDFParams()
+	h.stanzas = []Stanza{passwordStanza(p), passwordStanza(p)}
+	start := time.Now()
+	if _, err := h.unwrap([]Identity{NewPasswordIdentity("x")}); err == nil {
+		t.Fatal("header com duas stanzas de senha aceito")
+	}
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("recusa levou %s (KDF executado?)", took)
+	}
+}
+
+func TestCalibrateKDFWithinLimits(t *testing.T) {
+	p, _, err := CalibrateKDF(time.Hour, minKDFMemory, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("calibração fora dos limites: %v", err)
+	}
+}
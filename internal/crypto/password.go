@@ -0,0 +1,86 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Stanza de senha: Parâmetros do KDF (10) | Salt (16) | Chave de arquivo
+// cifrada (32 + tag 16). Nonce zero: a chave de embrulho é única por salt.
+const (
+	passwordSaltSize    = 16
+	passwordWrappedSize = fileKeySize + chacha20poly1305.Overhead
+	passwordBodySize    = kdfParamsSize + passwordSaltSize + passwordWrappedSize
+)
+
+var passwordLabel = []byte("ncc3 password")
+
+// PasswordRecipient: Destinatário por senha
+type PasswordRecipient struct {
+	password string
+	params   KDFParams
+}
+
+// NewPasswordRecipient: Senha com os custos padrão do KDF
+func NewPasswordRecipient(password string) *PasswordRecipient {
+	return &PasswordRecipient{password: password, params: DefaultKDFParams()}
+}
+
+// NewPasswordRecipientKDF: Senha com custos escolhidos (gravados na stanza)
+func NewPasswordRecipientKDF(password string, params KDFParams) (*PasswordRecipient, error) {
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	return &PasswordRecipient{password: password, params: params}, nil
+}
+
+func (r *PasswordRecipient) Wrap(fileKey []byte) (Stanza, error) {
+	salt := make([]byte, passwordSaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return Stanza{}, err
+	}
+	aead, err := chacha20poly1305.New(r.params.derive(r.password, salt))
+	if err != nil {
+		return Stanza{}, err
+	}
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	body := append(r.params.encode(), salt...)
+	return Stanza{Type: StanzaPassword, Body: aead.Seal(body, nonce, fileKey, passwordLabel)}, nil
+}
+
+// PasswordIdentity: Identidade por senha (também abre payloads NCC2)
+type PasswordIdentity struct {
+	password string
+}
+
+func NewPasswordIdentity(password string) *PasswordIdentity {
+	return &PasswordIdentity{password: password}
+}
+
+func (i *PasswordIdentity) Unwrap(s Stanza) ([]byte, error) {
+	if s.Type != StanzaPassword {
+		return nil, ErrNotRecipient
+	}
+
+	if len(s.Body) != passwordBodySize {
+		return nil, errDecrypt
+	}
+	params, err := decodeKDFParams(s.Body)
+	if err != nil {
+		return nil, err
+	}
+	rest := s.Body[kdfParamsSize:]
+	salt := rest[:passwordSaltSize]
+	aead, err := chacha20poly1305.New(params.derive(i.password, salt))
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	fileKey, err := aead.Open(nil, nonce, rest[passwordSaltSize:], passwordLabel)
+	if err != nil {
+		return nil, errDecrypt
+	}
+	return fileKey, nil
+}
@@ -0,0 +1,273 @@
+package crypto
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Stanza de partes (Shamir k-de-n): ID do conjunto (8) | k u8 | n u8. A
+// chave de arquivo não vai no header: ela é dividida em n partes em
+// GF(256), e quaisquer k reconstroem a chave (conferida pelo MAC do header).
+const (
+	StanzaShares = 3
+
+	shareSetIDSize    = 8
+	sharesBodySize    = shareSetIDSize + 2
+	shareChecksumSize = 4
+
+	// Formato texto das partes (base64 URL sem padding):
+	// ID (8) | k | n | x | y (32) | checksum SHA-256 (4)
+	SharePrefix = "NCC-SHARE-"
+)
+
+// ErrShares: Partes insuficientes ou de outro conjunto
+var ErrShares = errors.New("not enough key shares")
+
+// ErrMixedShares: Header com stanza de partes e outros destinatários
+var ErrMixedShares = errors.New("key shares mixed with other recipients")
+
+// Share: Uma parte da chave de arquivo (ponto x, y do polinômio)
+type Share struct {
+	SetID [shareSetIDSize]byte
+	K, N  byte
+	X     byte
+	Y     []byte
+}
+
+// ShareRecipient: Divide a chave de arquivo em N partes (K necessárias). As
+// partes ficam em Shares após o Wrap, para exportação.
+type ShareRecipient struct {
+	K, N   int
+	Shares []Share
+}
+
+// NewShareRecipient: k-de-n, 2 <= k <= n <= 255
+func NewShareRecipient(k, n int) (*ShareRecipient, error) {
+	if k < 2 || k > n || n > 255 {
+		return nil, fmt.Errorf("partes inválidas: %d de %d (use 2 <= k <= n <= 255)", k, n)
+	}
+	return &ShareRecipient{K: k, N: n}, nil
+}
+
+func (r *ShareRecipient) Wrap(fileKey []byte) (Stanza, error) {
+	var id [shareSetIDSize]byte
+	if _, err := io.ReadFull(rand.Reader, id[:]); err != nil {
+		return Stanza{}, err
+	}
+	ys, err := splitSecret(fileKey, r.K, r.N)
+	if err != nil {
+		return Stanza{}, err
+	}
+	r.Shares = r.Shares[:0]
+	for i, y := range ys {
+		r.Shares = append(r.Shares, Share{SetID: id, K: byte(r.K), N: byte(r.N), X: byte(i + 1), Y: y})
+	}
+	body := append(id[:], byte(r.K), byte(r.N))
+	return Stanza{Type: StanzaShares, Body: body}, nil
+}
+
+// ShareIdentity: Partes reunidas no decode
+type ShareIdentity struct {
+	shares []Share
+}
+
+// NewShareIdentity: Identidade das partes dadas (de um ou mais conjuntos)
+func NewShareIdentity(shares []Share) *ShareIdentity {
+	return &ShareIdentity{shares: shares}
+}
+
+func (i *ShareIdentity) Unwrap(s Stanza) ([]byte, error) {
+	if s.Type != StanzaShares {
+		return nil, ErrNotRecipient
+	}
+	if len(s.Body) != sharesBodySize {
+		return nil, errDecrypt
+	}
+	var id [shareSetIDSize]byte
+	copy(id[:], s.Body)
+	k := int(s.Body[shareSetIDSize])
+
+	// Só partes do conjunto da stanza; x repetido conta uma vez
+	seen := make(map[byte]bool)
+	var xs []byte
+	var ys [][]byte
+	for _, sh := range i.shares {
+		if sh.SetID != id || seen[sh.X] {
+			continue
+		}
+		seen[sh.X] = true
+		xs = append(xs, sh.X)
+		ys = append(ys, sh.Y)
+	}
+	if len(xs) == 0 {
+		return nil, ErrNotRecipient
+	}
+	if len(xs) < k {
+		return nil, fmt.Errorf("%w: %d de %d necessárias", ErrShares, len(xs), k)
+	}
+	return combineShares(xs[:k], ys[:k])
+}
+
+// String: Forma texto da parte
+func (s Share) String() string {
+	raw := append(s.SetID[:], s.K, s.N, s.X)
+	raw = append(raw, s.Y...)
+	sum := sha256.Sum256(raw)
+	raw = append(raw, sum[:shareChecksumSize]...)
+	return SharePrefix + base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// ParseShare: Parte no formato "NCC-SHARE-..." (checksum detecta erro de
+// digitação)
+func ParseShare(s string) (Share, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, SharePrefix) {
+		return Share{}, fmt.Errorf("parte inválida (esperado %s...)", SharePrefix)
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s[len(SharePrefix):])
+	if err != nil || len(raw) != shareSetIDSize+3+fileKeySize+shareChecksumSize {
+		return Share{}, fmt.Errorf("parte inválida")
+	}
+	body, check := raw[:len(raw)-shareChecksumSize], raw[len(raw)-shareChecksumSize:]
+	if sum := sha256.Sum256(body); !bytes.Equal(sum[:shareChecksumSize], check) {
+		return Share{}, fmt.Errorf("parte inválida (checksum)")
+	}
+	var sh Share
+	copy(sh.SetID[:], body)
+	sh.K, sh.N, sh.X = body[shareSetIDSize], body[shareSetIDSize+1], body[shareSetIDSize+2]
+	sh.Y = body[shareSetIDSize+3:]
+	if sh.X == 0 || sh.K < 2 || sh.K > sh.N {
+		return Share{}, fmt.Errorf("parte inválida")
+	}
+	return sh, nil
+}
+
+// WriteShareFile: Arquivo de uma parte (comentários + parte)
+func WriteShareFile(w io.Writer, s Share, archive string) error {
+	_, err := fmt.Fprintf(w, "# ncc share %d of %d (%d needed)\n# archive: %s\n%s\n",
+		s.X, s.N, s.K, archive, s)
+	return err
+}
+
+// ParseShareFile: Partes de um arquivo (linhas vazias e comentários "#"
+// ignorados)
+func ParseShareFile(r io.Reader) ([]Share, error) {
+	var out []Share
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sh, err := ParseShare(line)
+		if err != nil {
+			return nil, fmt.Errorf("linha %d: %w", n, err)
+		}
+		out = append(out, sh)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("nenhuma parte encontrada")
+	}
+	return out, nil
+}
+
+// splitSecret: Para cada byte, polinômio aleatório de grau k-1 com termo
+// constante = byte; parte i = valores em x = i+1
+func splitSecret(secret []byte, k, n int) ([][]byte, error) {
+	coeffs := make([]byte, k)
+	ys := make([][]byte, n)
+	for i := range ys {
+		ys[i] = make([]byte, len(secret))
+	}
+	for j, b := range secret {
+		coeffs[0] = b
+		if _, err := io.ReadFull(rand.Reader, coeffs[1:]); err != nil {
+			return nil, err
+		}
+		for i := range ys {
+			x := byte(i + 1)
+			var y byte // Horner
+			for c := k - 1; c >= 0; c-- {
+				y = gfMul(y, x) ^ coeffs[c]
+			}
+			ys[i][j] = y
+		}
+	}
+	return ys, nil
+}
+
+// combineShares: Interpolação de Lagrange em x = 0
+func combineShares(xs []byte, ys [][]byte) ([]byte, error) {
+	size := len(ys[0])
+	for _, y := range ys {
+		if len(y) != size {
+			return nil, errDecrypt
+		}
+	}
+	secret := make([]byte, size)
+	for i, xi := range xs {
+		// l_i(0) = prod x_j / (x_j - x_i); subtração = XOR em GF(2^8)
+		var num, den byte = 1, 1
+		for j, xj := range xs {
+			if i == j {
+				continue
+			}
+			num = gfMul(num, xj)
+			den = gfMul(den, xj^xi)
+		}
+		l := gfMul(num, gfInv(den))
+		for b := range secret {
+			secret[b] ^= gfMul(ys[i][b], l)
+		}
+	}
+	return secret, nil
+}
+
+// Tabelas de GF(2^8) (polinômio 0x11b, gerador 3)
+var gfExp, gfLog = func() (exp [510]byte, log [256]byte) {
+	x := byte(1)
+	for i := 0; i < 255; i++ {
+		exp[i], exp[i+255] = x, x
+		log[x] = byte(i)
+		x ^= gfMulSlow(x, 2) // x *= 3
+	}
+	return
+}()
+
+func gfMulSlow(a, b byte) byte {
+	var p byte
+	for b > 0 {
+		if b&1 == 1 {
+			p ^= a
+		}
+		hi := a & 0x80
+		a <<= 1
+		if hi != 0 {
+			a ^= 0x1b
+		}
+		b >>= 1
+	}
+	return p
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+// gfInv: Inverso multiplicativo (a != 0)
+func gfInv(a byte) byte {
+	return gfExp[255-int(gfLog[a])]
+}
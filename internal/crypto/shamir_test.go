@@ -0,0 +1,130 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// subsets: Todos os subconjuntos de k índices em [0, n)
+func subsets(n, k int) [][]int {
+	if k == 0 {
+		return [][]int{nil}
+	}
+	var out [][]int
+	for first := 0; first <= n-k; first++ {
+		for _, rest := range subsets(n-first-1, k-1) {
+			set := []int{first}
+			for _, i := range rest {
+				set = append(set, first+1+i)
+			}
+			out = append(out, set)
+		}
+	}
+	return out
+}
+
+func TestSharesSubsets(t *testing.T) {
+	for _, tc := range []struct{ k, n int }{{2, 2}, {2, 3}, {3, 5}, {4, 6}} {
+		r, err := NewShareRecipient(tc.k, tc.n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		plain := bytes.Repeat([]byte("shamir"), 1000)
+		ct, err := Encrypt(plain, testBinding, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(r.Shares) != tc.n {
+			t.Fatalf("%d-de-%d: %d partes", tc.k, tc.n, len(r.Shares))
+		}
+
+		// Quaisquer k partes decifram
+		for _, set := range subsets(tc.n, tc.k) {
+			var picked []Share
+			for _, i := range set {
+				picked = append(picked, r.Shares[i])
+			}
+			got, err := openTest(ct, NewShareIdentity(picked))
+			if err != nil {
+				t.Fatalf("%d-de-%d, partes %v: %v", tc.k, tc.n, set, err)
+			}
+			if !bytes.Equal(got, plain) {
+				t.Fatalf("%d-de-%d, partes %v: plaintext difere", tc.k, tc.n, set)
+			}
+		}
+
+		// k-1 partes (mesmo com repetidas) não decifram
+		for _, set := range subsets(tc.n, tc.k-1) {
+			var picked []Share
+			for _, i := range set {
+				picked = append(picked, r.Shares[i], r.Shares[i])
+			}
+			if _, err := openTest(ct, NewShareIdentity(picked)); !errors.Is(err, ErrShares) {
+				t.Fatalf("%d-de-%d, partes %v: err = %v, want ErrShares", tc.k, tc.n, set, err)
+			}
+		}
+	}
+}
+
+func TestShareText(t *testing.T) {
+	r, err := NewShareRecipient(2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Encrypt(nil, testBinding, r); err != nil {
+		t.Fatal(err)
+	}
+	var file bytes.Buffer
+	for _, sh := range r.Shares {
+		parsed, err := ParseShare(sh.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed.SetID != sh.SetID || parsed.X != sh.X || !bytes.Equal(parsed.Y, sh.Y) {
+			t.Fatalf("parte %d difere após o texto", sh.X)
+		}
+		if err := WriteShareFile(&file, sh, "test.nccv"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	parsed, err := ParseShareFile(&file)
+	if err != nil || len(parsed) != len(r.Shares) {
+		t.Fatalf("arquivo de partes: %d partes, %v", len(parsed), err)
+	}
+
+	// Erro de digitação: checksum
+	text := []byte(r.Shares[0].String())
+	text[len(SharePrefix)+5] ^= 1
+	if _, err := ParseShare(string(text)); err == nil {
+		t.Error("parte adulterada aceita")
+	}
+}
+
+func TestSharesMixedStanzas(t *testing.T) {
+	id, err := GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewShareRecipient(2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Encrypt(nil, testBinding, r, id.Recipient()); !errors.Is(err, ErrMixedShares) {
+		t.Fatalf("encrypt: err = %v, want ErrMixedShares", err)
+	}
+
+	// Header forjado: stanza de partes + stanza X25519 que a identidade abre
+	fileKey := bytes.Repeat([]byte{9}, fileKeySize)
+	h := &streamHeader{binding: testBinding, nonce: make([]byte, streamNonceSize)}
+	for _, rc := range []Recipient{r, id.Recipient()} {
+		s, err := rc.Wrap(fileKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.stanzas = append(h.stanzas, s)
+	}
+	if _, err := h.unwrap([]Identity{id}); !errors.Is(err, ErrMixedShares) {
+		t.Fatalf("decrypt: err = %v, want ErrMixedShares", err)
+	}
+}
@@ -0,0 +1,206 @@
+package crypto
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Chaves de assinatura em texto (base64 URL sem padding)
+const (
+	SigningKeyPrefix = "NCC-SIGN-KEY-"
+	VerifyKeyPrefix  = "ncc-sig-"
+)
+
+// Domínios: separam as assinaturas do NCC de outros usos da chave (e a
+// assinatura do hash da assinatura da raiz Merkle)
+const (
+	signatureDomain       = "ncc signature v1\x00"
+	merkleSignatureDomain = "ncc merkle signature v1\x00"
+)
+
+// SigningKey: Chave privada Ed25519
+type SigningKey struct {
+	priv ed25519.PrivateKey
+}
+
+// VerifyKey: Chave pública Ed25519
+type VerifyKey struct {
+	pub ed25519.PublicKey
+}
+
+// GenerateSigningKey: Novo par Ed25519
+func GenerateSigningKey() (*SigningKey, error) {
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &SigningKey{priv: priv}, nil
+}
+
+func (k *SigningKey) String() string {
+	return SigningKeyPrefix + base64.RawURLEncoding.EncodeToString(k.priv.Seed())
+}
+
+// Public: Chave de verificação correspondente
+func (k *SigningKey) Public() *VerifyKey {
+	return &VerifyKey{pub: k.priv.Public().(ed25519.PublicKey)}
+}
+
+func (k *VerifyKey) String() string {
+	return VerifyKeyPrefix + base64.RawURLEncoding.EncodeToString(k.pub)
+}
+
+// Equal: Mesma chave pública
+func (k *VerifyKey) Equal(o *VerifyKey) bool {
+	return k.pub.Equal(o.pub)
+}
+
+// ParseVerifyKey: Chave pública no formato "ncc-sig-..."
+func ParseVerifyKey(s string) (*VerifyKey, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, VerifyKeyPrefix) {
+		return nil, fmt.Errorf("chave de verificação inválida (esperado %s...): %q", VerifyKeyPrefix, s)
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s[len(VerifyKeyPrefix):])
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("chave de verificação inválida: %q", s)
+	}
+	return &VerifyKey{pub: ed25519.PublicKey(raw)}, nil
+}
+
+// parseSigningKey: Chave privada no formato "NCC-SIGN-KEY-..."
+func parseSigningKey(s string) (*SigningKey, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(s, SigningKeyPrefix))
+	if err != nil || len(raw) != ed25519.SeedSize {
+		return nil, errors.New("chave de assinatura inválida")
+	}
+	return &SigningKey{priv: ed25519.NewKeyFromSeed(raw)}, nil
+}
+
+// WriteSigningKeyFile: Arquivo da chave de assinatura (comentários + chave)
+func WriteSigningKeyFile(w io.Writer, k *SigningKey) error {
+	_, err := fmt.Fprintf(w, "# created: %s\n# verify key: %s\n%s\n",
+		time.Now().Format(time.RFC3339), k.Public(), k)
+	return err
+}
+
+// ParseSigningKeyFile: Chave de assinatura de um arquivo (linhas vazias e
+// comentários ignorados)
+func ParseSigningKeyFile(r io.Reader) (*SigningKey, error) {
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !strings.HasPrefix(line, SigningKeyPrefix) {
+			return nil, fmt.Errorf("chave de assinatura inválida (esperado %s...)", SigningKeyPrefix)
+		}
+		return parseSigningKey(line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return nil, errors.New("nenhuma chave de assinatura encontrada")
+}
+
+// ParseVerifyKeyFile: Chave de verificação de um arquivo (aceita também o
+// arquivo da chave de assinatura)
+func ParseVerifyKeyFile(r io.Reader) (*VerifyKey, error) {
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, SigningKeyPrefix) {
+			k, err := parseSigningKey(line)
+			if err != nil {
+				return nil, err
+			}
+			return k.Public(), nil
+		}
+		return ParseVerifyKey(line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return nil, errors.New("nenhuma chave de verificação encontrada")
+}
+
+// signedMessage: Domínio | Digest do payload | descritor da configuração
+func signedMessage(domain string, digest [32]byte, descriptor string) []byte {
+	msg := append([]byte(domain), digest[:]...)
+	return append(msg, descriptor...)
+}
+
+// Signature: Assinatura do payload com a configuração que o carrega
+type Signature struct {
+	Key        *VerifyKey
+	Sig        []byte
+	Descriptor string // Configuração de frame/ECC (ou "audio", "stego")
+}
+
+// SignPayload: Assina o hash do payload e o descritor da configuração
+func SignPayload(k *SigningKey, payloadHash [32]byte, descriptor string) Signature {
+	return sign(k, signatureDomain, payloadHash, descriptor)
+}
+
+// SignMerkleRoot: Assina a raiz Merkle dos chunks do payload (verificável
+// com parte do payload perdida)
+func SignMerkleRoot(k *SigningKey, root [32]byte, descriptor string) Signature {
+	return sign(k, merkleSignatureDomain, root, descriptor)
+}
+
+func sign(k *SigningKey, domain string, digest [32]byte, descriptor string) Signature {
+	return Signature{
+		Key:        k.Public(),
+		Sig:        ed25519.Sign(k.priv, signedMessage(domain, digest, descriptor)),
+		Descriptor: descriptor,
+	}
+}
+
+// Verify: Assinatura válida para o hash do payload
+func (s Signature) Verify(payloadHash [32]byte) bool {
+	return ed25519.Verify(s.Key.pub, signedMessage(signatureDomain, payloadHash, s.Descriptor), s.Sig)
+}
+
+// VerifyMerkleRoot: Assinatura válida para a raiz Merkle
+func (s Signature) VerifyMerkleRoot(root [32]byte) bool {
+	return ed25519.Verify(s.Key.pub, signedMessage(merkleSignatureDomain, root, s.Descriptor), s.Sig)
+}
+
+// Encode: Chave pública (32) | Assinatura (64) | Descritor
+func (s Signature) Encode() []byte {
+	out := append([]byte(nil), s.Key.pub...)
+	out = append(out, s.Sig...)
+	return append(out, s.Descriptor...)
+}
+
+// DecodeSignature: Inverso de Encode
+func DecodeSignature(b []byte) (Signature, error) {
+	if len(b) < ed25519.PublicKeySize+ed25519.SignatureSize {
+		return Signature{}, errors.New("registro de assinatura truncado")
+	}
+	return Signature{
+		Key:        &VerifyKey{pub: ed25519.PublicKey(b[:ed25519.PublicKeySize])},
+		Sig:        b[ed25519.PublicKeySize : ed25519.PublicKeySize+ed25519.SignatureSize],
+		Descriptor: string(b[ed25519.PublicKeySize+ed25519.SignatureSize:]),
+	}, nil
+}
+
+// HashPayload: SHA-256 de um leitor (payload em streaming)
+func HashPayload(r io.Reader) ([32]byte, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return [32]byte{}, err
+	}
+	return [32]byte(h.Sum(nil)), nil
+}
@@ -0,0 +1,317 @@
+package crypto
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Segmentos STREAM: plaintext em blocos de SegmentSize, cada um selado com
+// nonce = contador (11 bytes, big endian) | flag de último segmento. Um
+// segmento corrompido não afeta os anteriores; truncamento e reordenação
+// são detectados.
+const (
+	SegmentSize       = 64 * 1024
+	SegmentSealedSize = SegmentSize + chacha20poly1305.Overhead
+)
+
+// sealedSize: Bytes dos segmentos selados de n bytes de plaintext (um
+// segmento vazio para n = 0)
+func sealedSize(n int) int {
+	segments := max(1, (n+SegmentSize-1)/SegmentSize)
+	return n + segments*chacha20poly1305.Overhead
+}
+
+// segmentNonce: Contador + flag de último
+func segmentNonce(counter uint64, last bool) []byte {
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	binary.BigEndian.PutUint64(nonce[3:11], counter)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+// NewWriter: Cifra NCC3 para os destinatários, ligada ao carrier por b.
+// Close sela o último segmento (obrigatório).
+func NewWriter(w io.Writer, b Binding, recipients ...Recipient) (io.WriteCloser, error) {
+	fileKey := make([]byte, fileKeySize)
+	if _, err := io.ReadFull(rand.Reader, fileKey); err != nil {
+		return nil, err
+	}
+	return newWriter(w, fileKey, b, recipients)
+}
+
+func newWriter(w io.Writer, fileKey []byte, b Binding, recipients []Recipient) (io.WriteCloser, error) {
+	h, err := newHeader(fileKey, recipients, b)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := segmentAEAD(fileKey, h.nonce)
+	if err != nil {
+		return nil, err
+	}
+	aad, err := h.aad()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(h.bytes()); err != nil {
+		return nil, err
+	}
+	return &streamWriter{w: w, aead: aead, aad: aad, buf: make([]byte, 0, SegmentSize)}, nil
+}
+
+func segmentAEAD(fileKey, nonce []byte) (cipher.AEAD, error) {
+	key, err := payloadKey(fileKey, nonce)
+	if err != nil {
+		return nil, err
+	}
+	return chacha20poly1305.New(key)
+}
+
+// streamWriter: Segmento cheio só é selado quando chegam mais dados (o
+// último segmento é conhecido apenas no Close)
+type streamWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	aad     []byte // Binding (versão + carrier) em todo segmento
+	buf     []byte
+	counter uint64
+	closed  bool
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	if s.closed {
+		return 0, errors.New("write to closed NCC3 writer")
+	}
+	n := 0
+	for len(p) > 0 {
+		if len(s.buf) == SegmentSize {
+			if err := s.flush(false); err != nil {
+				return n, err
+			}
+		}
+		k := copy(s.buf[len(s.buf):SegmentSize], p)
+		s.buf = s.buf[:len(s.buf)+k]
+		p = p[k:]
+		n += k
+	}
+	return n, nil
+}
+
+func (s *streamWriter) flush(last bool) error {
+	sealed := s.aead.Seal(nil, segmentNonce(s.counter, last), s.buf, s.aad)
+	s.counter++
+	s.buf = s.buf[:0]
+	_, err := s.w.Write(sealed)
+	return err
+}
+
+func (s *streamWriter) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.flush(true)
+}
+
+// NewReader: Decifra NCC3 em streaming, segmento a segmento. Cada
+// segmento é verificado antes de ser entregue; o primeiro inválido
+// interrompe a leitura com erro. O binding autenticado (v2) precisa
+// conferir com o observado no carrier (ErrBinding).
+func NewReader(r io.Reader, observed Binding, identities ...Identity) (io.Reader, error) {
+	return newReader(r, observed, nil, identities)
+}
+
+// SkipReport: Segmentos que o leitor tolerante (DecryptSkipping) não
+// conseguiu abrir
+type SkipReport struct {
+	Segments  []uint64 // Entregues como zeros do mesmo tamanho
+	Truncated bool     // O fluxo acaba antes do segmento final
+}
+
+// NewSkippingReader: Como NewReader, mas um segmento inválido vira zeros
+// do mesmo tamanho e a leitura continua: os segmentos têm tamanho fixo,
+// então o contador de cada posição é conhecido. Os pulados ficam em report.
+func NewSkippingReader(r io.Reader, observed Binding, report *SkipReport, identities ...Identity) (io.Reader, error) {
+	return newReader(r, observed, report, identities)
+}
+
+func newReader(r io.Reader, observed Binding, skip *SkipReport, identities []Identity) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	h, err := readHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	fileKey, err := h.unwrap(identities)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.binding.Check(observed); err != nil {
+		return nil, err
+	}
+	aead, err := segmentAEAD(fileKey, h.nonce)
+	if err != nil {
+		return nil, err
+	}
+	aad, err := h.aad()
+	if err != nil {
+		return nil, err
+	}
+	return &streamReader{r: br, aead: aead, aad: aad, sealed: make([]byte, SegmentSealedSize), skip: skip}, nil
+}
+
+type streamReader struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	aad     []byte
+	sealed  []byte
+	plain   []byte // Restante do segmento atual
+	counter uint64
+	done    bool
+	err     error
+	skip    *SkipReport // nil = estrito
+}
+
+func (s *streamReader) Read(p []byte) (int, error) {
+	for len(s.plain) == 0 {
+		if s.err != nil {
+			return 0, s.err
+		}
+		if s.done {
+			return 0, io.EOF
+		}
+		s.err = s.next()
+	}
+	n := copy(p, s.plain)
+	s.plain = s.plain[n:]
+	return n, nil
+}
+
+// next: Lê e abre o próximo segmento. É o último se terminar o fluxo.
+func (s *streamReader) next() error {
+	n, err := io.ReadFull(s.r, s.sealed)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return err
+	}
+	last := n < len(s.sealed)
+	if !last {
+		if _, err := s.r.Peek(1); err == io.EOF {
+			last = true
+		}
+	}
+	if n < chacha20poly1305.Overhead {
+		if s.skip != nil {
+			s.skip.Truncated = true
+			s.done = true
+			return nil
+		}
+		return fmt.Errorf("%w (NCC3 truncado no segmento %d)", errDecrypt, s.counter)
+	}
+
+	// Open em falha zera o destino: no modo tolerante o selado é preservado
+	// para a segunda tentativa
+	sealed, dst := s.sealed[:n], s.sealed[:0]
+	if s.skip != nil {
+		dst = nil
+	}
+	plain, err := s.aead.Open(dst, segmentNonce(s.counter, last), sealed, s.aad)
+	if err != nil && s.skip != nil {
+		plain, err = s.skipSegment(sealed, last)
+	}
+	if err != nil {
+		return fmt.Errorf("%w (segmento %d)", errDecrypt, s.counter)
+	}
+	if last && len(plain) == 0 && s.counter > 0 {
+		return fmt.Errorf("%w (segmento final vazio)", errDecrypt)
+	}
+	s.counter++
+	s.done = last
+	s.plain = plain
+	return nil
+}
+
+// skipSegment: Modo tolerante: o último segmento lido que abre sem a flag
+// de último é autêntico, mas o fluxo foi truncado depois dele; fora isso o
+// segmento vira zeros do tamanho do plaintext
+func (s *streamReader) skipSegment(sealed []byte, last bool) ([]byte, error) {
+	if last {
+		if plain, err := s.aead.Open(nil, segmentNonce(s.counter, false), sealed, s.aad); err == nil {
+			s.skip.Truncated = true
+			return plain, nil
+		}
+	}
+	s.skip.Segments = append(s.skip.Segments, s.counter)
+	return make([]byte, len(sealed)-chacha20poly1305.Overhead), nil
+}
+
+// Encrypt: Cifra NCC3 em memória
+func Encrypt(plaintext []byte, b Binding, recipients ...Recipient) ([]byte, error) {
+	out, _, err := EncryptArchive(plaintext, b, recipients...)
+	return out, err
+}
+
+// EncryptArchive: Como Encrypt, retornando também a chave de arquivo para
+// as chaves derivadas (índice Merkle, embaralhamento)
+func EncryptArchive(plaintext []byte, b Binding, recipients ...Recipient) ([]byte, *ArchiveKeys, error) {
+	fileKey := make([]byte, fileKeySize)
+	if _, err := io.ReadFull(rand.Reader, fileKey); err != nil {
+		return nil, nil, err
+	}
+	var buf bytes.Buffer
+	w, err := newWriter(&buf, fileKey, b, recipients)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		return nil, nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, nil, err
+	}
+	out := buf.Bytes()
+	headerSize := len(out) - sealedSize(len(plaintext))
+	return out, &ArchiveKeys{fileKey: fileKey, headerSize: headerSize}, nil
+}
+
+// Decrypt: Leitor do plaintext de um payload cifrado. NCC3 é decifrado em
+// streaming; o NCC2 legado (sem magic em claro) é lido inteiro e aberto
+// com a senha de uma PasswordIdentity.
+func Decrypt(r io.Reader, observed Binding, identities ...Identity) (io.Reader, error) {
+	return decrypt(r, observed, nil, identities)
+}
+
+// DecryptSkipping: Como Decrypt, mas o NCC3 é lido por NewSkippingReader
+// (-partial). O NCC2 legado é uma mensagem só e não tem o que pular.
+func DecryptSkipping(r io.Reader, observed Binding, report *SkipReport, identities ...Identity) (io.Reader, error) {
+	return decrypt(r, observed, report, identities)
+}
+
+func decrypt(r io.Reader, observed Binding, skip *SkipReport, identities []Identity) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	if prefix, _ := br.Peek(len(StreamMagic)); IsStream(prefix) {
+		return newReader(br, observed, skip, identities)
+	}
+
+	for _, id := range identities {
+		if pw, ok := id.(*PasswordIdentity); ok {
+			data, err := io.ReadAll(br)
+			if err != nil {
+				return nil, err
+			}
+			plaintext, err := DecryptWithHash(data, pw.password)
+			if err != nil {
+				return nil, err
+			}
+			return bytes.NewReader(plaintext), nil
+		}
+	}
+	return nil, errors.New("failed to decrypt: legacy NCC2 payload requires a password")
+}
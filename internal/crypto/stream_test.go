@@ -0,0 +1,220 @@
+package crypto
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+var testBinding = Binding{Descriptor: "test", ArchiveID: [6]byte{1, 2, 3, 4, 5, 6}}
+
+// sealTest: Plaintext aleatório de size bytes cifrado para uma identidade
+// X25519; retorna também o tamanho do header
+func sealTest(t *testing.T, size int) (*X25519Identity, []byte, []byte, int) {
+	t.Helper()
+	id, err := GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := make([]byte, size)
+	rand.New(rand.NewSource(int64(size))).Read(plain)
+	ct, err := Encrypt(plain, testBinding, id.Recipient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := readHeader(bufio.NewReader(bytes.NewReader(ct)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id, plain, ct, len(h.bytes())
+}
+
+func openTest(ct []byte, id Identity) ([]byte, error) {
+	r, err := Decrypt(bytes.NewReader(ct), testBinding, id)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestStreamRoundTrip(t *testing.T) {
+	for _, size := range []int{0, 1, SegmentSize - 1, SegmentSize, 3 * SegmentSize, 3*SegmentSize + 100} {
+		id, plain, ct, hdr := sealTest(t, size)
+		segments := max(1, (size+SegmentSize-1)/SegmentSize)
+		if want := hdr + size + segments*(SegmentSealedSize-SegmentSize); len(ct) != want {
+			t.Errorf("%d bytes: cifrado com %d bytes, want %d", size, len(ct), want)
+		}
+		got, err := openTest(ct, id)
+		if err != nil {
+			t.Fatalf("%d bytes: %v", size, err)
+		}
+		if !bytes.Equal(got, plain) {
+			t.Fatalf("%d bytes: plaintext difere", size)
+		}
+	}
+}
+
+func TestStreamRejectsTampering(t *testing.T) {
+	id, _, ct, hdr := sealTest(t, 3*SegmentSize+100)
+	seg := func(i int) []byte { // Segmento i selado
+		start := hdr + i*SegmentSealedSize
+		return ct[start:min(start+SegmentSealedSize, len(ct))]
+	}
+
+	var swapped []byte // Segmentos 0 e 1 trocados
+	swapped = append(swapped, ct[:hdr]...)
+	swapped = append(swapped, seg(1)...)
+	swapped = append(swapped, seg(0)...)
+	swapped = append(swapped, ct[hdr+2*SegmentSealedSize:]...)
+
+	// Último segmento removido: o 2 passa a ser o final, sem a flag
+	dropped := ct[:hdr+3*SegmentSealedSize]
+
+	// Segmento final corrompido
+	corrupted := append([]byte(nil), ct...)
+	corrupted[hdr+3*SegmentSealedSize+5] ^= 1
+
+	// Outra versão do header (sem binding não há rebaixamento)
+	version := append([]byte(nil), ct...)
+	version[len(StreamMagic)] = streamVersion + 1
+
+	for _, tc := range []struct {
+		name string
+		ct   []byte
+	}{
+		{"truncado no meio de um segmento", ct[:len(ct)-50]},
+		{"truncado na fronteira", dropped},
+		{"sem header", ct[:hdr-1]},
+		{"reordenado", swapped},
+		{"segmento final corrompido", corrupted},
+		{"versão desconhecida", version},
+	} {
+		if _, err := openTest(tc.ct, id); err == nil {
+			t.Errorf("%s: decifrado sem erro", tc.name)
+		}
+	}
+}
+
+func TestStreamLastFlag(t *testing.T) {
+	// Um fluxo de 2 segmentos cujo segmento 0 foi selado como último:
+	// o leitor precisa recusar dados depois dele
+	id, plain, ct, hdr := sealTest(t, SegmentSize/2)
+	h, err := readHeader(bufio.NewReader(bytes.NewReader(ct)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileKey, err := h.unwrap([]Identity{id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err := segmentAEAD(fileKey, h.nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aad, err := h.aad()
+	if err != nil {
+		t.Fatal(err)
+	}
+	full := make([]byte, SegmentSize)
+	forged := append([]byte(nil), ct[:hdr]...)
+	forged = aead.Seal(forged, segmentNonce(0, true), full, aad)
+	forged = aead.Seal(forged, segmentNonce(1, true), plain, aad)
+	if _, err := openTest(forged, id); err == nil {
+		t.Error("segmento com flag de último no meio do fluxo aceito")
+	}
+
+	// Último segmento sem a flag (fluxo que deveria continuar)
+	noLast := aead.Seal(append([]byte(nil), ct[:hdr]...), segmentNonce(0, false), plain, aad)
+	if _, err := openTest(noLast, id); err == nil {
+		t.Error("fluxo sem segmento final aceito")
+	}
+}
+
+func TestSkippingReader(t *testing.T) {
+	id, plain, ct, hdr := sealTest(t, 3*SegmentSize+100)
+	damaged := append([]byte(nil), ct...)
+	damaged[hdr+SegmentSealedSize+10] ^= 1 // Segmento 1
+
+	var report SkipReport
+	r, err := DecryptSkipping(bytes.NewReader(damaged), testBinding, &report, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(plain) {
+		t.Fatalf("%d bytes, want %d (posições preservadas)", len(got), len(plain))
+	}
+	if len(report.Segments) != 1 || report.Segments[0] != 1 || report.Truncated {
+		t.Fatalf("relatório = %+v, want segmento 1", report)
+	}
+	if !bytes.Equal(got[:SegmentSize], plain[:SegmentSize]) || !bytes.Equal(got[2*SegmentSize:], plain[2*SegmentSize:]) {
+		t.Error("segmentos íntegros diferem")
+	}
+	if !bytes.Equal(got[SegmentSize:2*SegmentSize], make([]byte, SegmentSize)) {
+		t.Error("segmento pulado não foi zerado")
+	}
+
+	// Truncado na fronteira: os segmentos lidos são entregues, com aviso
+	report = SkipReport{}
+	r, err = DecryptSkipping(bytes.NewReader(ct[:hdr+2*SegmentSealedSize]), testBinding, &report, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err = io.ReadAll(r); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plain[:2*SegmentSize]) || !report.Truncated || len(report.Segments) != 0 {
+		t.Fatalf("truncado: %d bytes, relatório %+v", len(got), report)
+	}
+}
+
+func TestArchiveKeysHeader(t *testing.T) {
+	id, err := GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct, archive, err := EncryptArchive(make([]byte, SegmentSize+1), testBinding, id.Recipient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	opened, err := OpenArchiveKeys(bytes.NewReader(ct), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdr := archive.HeaderSize()
+	if opened.HeaderSize() != hdr {
+		t.Fatalf("HeaderSize: %d ao cifrar, %d ao abrir", hdr, opened.HeaderSize())
+	}
+	if HeaderComplete(ct[:hdr-1]) || !HeaderComplete(ct[:hdr]) {
+		t.Error("HeaderComplete não marca o fim do header")
+	}
+	if !HeaderComplete(bytes.Repeat([]byte{0x1f, 0x8b}, 32)) {
+		t.Error("HeaderComplete espera mais bytes de um payload sem NCC3")
+	}
+
+	// Chave do scramble: mesma dos dois lados, distinta por arquivo
+	k1, err := archive.ScrambleKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	k2, err := opened.ScrambleKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, fresh, err := EncryptArchive(nil, testBinding, id.Recipient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	k3, err := fresh.ScrambleKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(k1, k2) || bytes.Equal(k1, k3) {
+		t.Error("ScrambleKey não segue a chave de arquivo")
+	}
+}
@@ -0,0 +1,221 @@
+package crypto
+
+import (
+	"bufio"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Stanza X25519: Chave pública efêmera (32) | Chave de arquivo cifrada
+// (32 + tag 16). Chave de embrulho = HKDF(ECDH, efêmera || destinatário).
+const (
+	StanzaX25519 = 2
+
+	x25519KeySize  = 32
+	x25519BodySize = x25519KeySize + fileKeySize + chacha20poly1305.Overhead
+
+	// Formato texto das chaves (base64 URL sem padding)
+	PublicKeyPrefix = "ncc-pub-"
+	SecretKeyPrefix = "NCC-SECRET-KEY-"
+)
+
+const x25519Label = "ncc3 x25519"
+
+// X25519Recipient: Destinatário por chave pública
+type X25519Recipient struct {
+	pub *ecdh.PublicKey
+}
+
+// ParseRecipient: Chave pública no formato "ncc-pub-..."
+func ParseRecipient(s string) (*X25519Recipient, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, PublicKeyPrefix) {
+		return nil, fmt.Errorf("chave pública inválida (esperado %s...): %q", PublicKeyPrefix, s)
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s[len(PublicKeyPrefix):])
+	if err != nil || len(raw) != x25519KeySize {
+		return nil, fmt.Errorf("chave pública inválida: %q", s)
+	}
+	pub, err := ecdh.X25519().NewPublicKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("chave pública inválida: %w", err)
+	}
+	return &X25519Recipient{pub: pub}, nil
+}
+
+// String: Forma texto da chave pública
+func (r *X25519Recipient) String() string {
+	return PublicKeyPrefix + base64.RawURLEncoding.EncodeToString(r.pub.Bytes())
+}
+
+func (r *X25519Recipient) Wrap(fileKey []byte) (Stanza, error) {
+	eph, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return Stanza{}, err
+	}
+	shared, err := eph.ECDH(r.pub)
+	if err != nil {
+		return Stanza{}, err
+	}
+	ephPub := eph.PublicKey().Bytes()
+	aead, err := x25519AEAD(shared, ephPub, r.pub.Bytes())
+	if err != nil {
+		return Stanza{}, err
+	}
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	body := aead.Seal(append([]byte(nil), ephPub...), nonce, fileKey, nil)
+	return Stanza{Type: StanzaX25519, Body: body}, nil
+}
+
+// X25519Identity: Chave secreta (arquivo de identidade)
+type X25519Identity struct {
+	priv *ecdh.PrivateKey
+}
+
+// GenerateX25519Identity: Novo par de chaves
+func GenerateX25519Identity() (*X25519Identity, error) {
+	priv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &X25519Identity{priv: priv}, nil
+}
+
+// ParseIdentity: Chave secreta no formato "NCC-SECRET-KEY-..."
+func ParseIdentity(s string) (*X25519Identity, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, SecretKeyPrefix) {
+		return nil, fmt.Errorf("chave secreta inválida (esperado %s...)", SecretKeyPrefix)
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s[len(SecretKeyPrefix):])
+	if err != nil || len(raw) != x25519KeySize {
+		return nil, fmt.Errorf("chave secreta inválida")
+	}
+	priv, err := ecdh.X25519().NewPrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("chave secreta inválida: %w", err)
+	}
+	return &X25519Identity{priv: priv}, nil
+}
+
+// String: Forma texto da chave secreta
+func (i *X25519Identity) String() string {
+	return SecretKeyPrefix + base64.RawURLEncoding.EncodeToString(i.priv.Bytes())
+}
+
+// Recipient: Chave pública correspondente
+func (i *X25519Identity) Recipient() *X25519Recipient {
+	return &X25519Recipient{pub: i.priv.PublicKey()}
+}
+
+func (i *X25519Identity) Unwrap(s Stanza) ([]byte, error) {
+	if s.Type != StanzaX25519 {
+		return nil, ErrNotRecipient
+	}
+	if len(s.Body) != x25519BodySize {
+		return nil, errDecrypt
+	}
+	ephPub := s.Body[:x25519KeySize]
+	eph, err := ecdh.X25519().NewPublicKey(ephPub)
+	if err != nil {
+		return nil, errDecrypt
+	}
+	shared, err := i.priv.ECDH(eph)
+	if err != nil {
+		return nil, errDecrypt
+	}
+	aead, err := x25519AEAD(shared, ephPub, i.priv.PublicKey().Bytes())
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	fileKey, err := aead.Open(nil, nonce, s.Body[x25519KeySize:], nil)
+	if err != nil {
+		return nil, ErrNotRecipient // Stanza de outra chave pública
+	}
+	return fileKey, nil
+}
+
+func x25519AEAD(shared, ephPub, recipientPub []byte) (cipher.AEAD, error) {
+	salt := append(append([]byte(nil), ephPub...), recipientPub...)
+	key, err := hkdf.Key(sha256.New, shared, salt, x25519Label, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	return chacha20poly1305.New(key)
+}
+
+// WriteIdentityFile: Arquivo de identidade (comentários + chave secreta)
+func WriteIdentityFile(w io.Writer, id *X25519Identity) error {
+	_, err := fmt.Fprintf(w, "# created: %s\n# public key: %s\n%s\n",
+		time.Now().Format(time.RFC3339), id.Recipient(), id)
+	return err
+}
+
+// ParseIdentities: Chaves secretas de um arquivo de identidade (linhas
+// vazias e comentários "#" ignorados)
+func ParseIdentities(r io.Reader) ([]*X25519Identity, error) {
+	var ids []*X25519Identity
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		id, err := ParseIdentity(line)
+		if err != nil {
+			return nil, fmt.Errorf("linha %d: %w", n, err)
+		}
+		ids = append(ids, id)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("nenhuma chave secreta encontrada")
+	}
+	return ids, nil
+}
+
+// ParseRecipients: Chaves públicas de um arquivo (uma por linha; aceita
+// também o arquivo de identidade, usando a chave pública das secretas)
+func ParseRecipients(r io.Reader) ([]*X25519Recipient, error) {
+	var out []*X25519Recipient
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, SecretKeyPrefix) {
+			id, err := ParseIdentity(line)
+			if err != nil {
+				return nil, fmt.Errorf("linha %d: %w", n, err)
+			}
+			out = append(out, id.Recipient())
+			continue
+		}
+		rec, err := ParseRecipient(line)
+		if err != nil {
+			return nil, fmt.Errorf("linha %d: %w", n, err)
+		}
+		out = append(out, rec)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("nenhuma chave pública encontrada")
+	}
+	return out, nil
+}
@@ -0,0 +1,92 @@
+package decoder
+
+import (
+	"fmt"
+	"os"
+)
+
+// payloadWriter: Monta o payload no arquivo de saída à medida que os frames
+// chegam, na ordem dos índices. Só frames fora de ordem (ou depois de uma
+// falta) ficam em memória; dos já escritos resta o header e o offset.
+type payloadWriter struct {
+	f       *os.File
+	frames  map[int]decodeResult // Por FrameIndex (data nil após a escrita)
+	offsets map[int]int64        // Offset de cada frame já escrito
+	next    int                  // Próximo índice a escrever
+	limit   int                  // Total do GlobalHeader (0 = desconhecido)
+	size    int64                // Bytes escritos
+}
+
+func newPayloadWriter(path string) (*payloadWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("criar saída: %w", err)
+	}
+	return &payloadWriter{
+		f:       f,
+		frames:  make(map[int]decodeResult),
+		offsets: make(map[int]int64),
+	}, nil
+}
+
+// add: Registra um frame decodificado. Duplicatas: mantém a cópia com CRC
+// válido.
+func (w *payloadWriter) add(idx int, res decodeResult) error {
+	if prev, ok := w.frames[idx]; ok && (prev.crcOK || !res.crcOK) {
+		return nil
+	}
+	return w.put(idx, res)
+}
+
+// put: Grava o frame idx: no lugar da cópia anterior, se já escrita (mesmo
+// tamanho), ou na sequência assim que os índices anteriores chegarem
+func (w *payloadWriter) put(idx int, res decodeResult) error {
+	res.size = len(res.data)
+	if off, ok := w.offsets[idx]; ok {
+		if prev := w.frames[idx]; prev.size != res.size {
+			return fmt.Errorf("frame %d: cópia com %d bytes, escrita com %d", idx, res.size, prev.size)
+		}
+		if _, err := w.f.WriteAt(res.data, off); err != nil {
+			return fmt.Errorf("escrever frame %d: %w", idx, err)
+		}
+		res.data = nil
+		w.frames[idx] = res
+		return nil
+	}
+	w.frames[idx] = res
+	return w.flush()
+}
+
+// flush: Escreve os frames contíguos a partir de next
+func (w *payloadWriter) flush() error {
+	for w.limit == 0 || w.next < w.limit {
+		res, ok := w.frames[w.next]
+		if !ok {
+			return nil
+		}
+		if w.next == 0 && res.frameHeader.HasGlobal == 1 {
+			w.limit = int(res.frameHeader.GlobalMeta.TotalFrames)
+		}
+		if _, err := w.f.WriteAt(res.data, w.size); err != nil {
+			return fmt.Errorf("escrever frame %d: %w", w.next, err)
+		}
+		w.offsets[w.next] = w.size
+		w.size += int64(len(res.data))
+		res.data = nil
+		w.frames[w.next] = res
+		w.next++
+	}
+	return nil
+}
+
+// gap: Lacuna zerada de n bytes no lugar do frame next (-partial). Retorna
+// o offset inicial.
+func (w *payloadWriter) gap(n int) (int64, error) {
+	start := w.size
+	if _, err := w.f.WriteAt(make([]byte, n), start); err != nil {
+		return 0, fmt.Errorf("escrever lacuna do frame %d: %w", w.next, err)
+	}
+	w.size += int64(n)
+	w.next++
+	return start, nil
+}
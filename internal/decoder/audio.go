@@ -0,0 +1,84 @@
+package decoder
+
+import (
+	"fmt"
+	"hash/crc32"
+	"os"
+
+	"ncc/internal/audio"
+	"ncc/internal/encoder"
+)
+
+// audioFallback: Recorre à faixa de áudio quando o vídeo não basta. Um
+// manifest repõe o frame 0 ausente ou com CRC inválido; um payload substitui
+// o vídeo quando faltam frames. Retorna o frame 0 restaurado (nil se não
+// houve) e true se o arquivo já foi escrito.
+func (fr *FrameReconstructor) audioFallback(byIndex map[int]decodeResult, outputPath string) (*decodeResult, bool) {
+	first, ok := byIndex[0]
+	needFirst := !ok || !first.crcOK || first.frameHeader.HasGlobal != 1
+	if !needFirst && !framesMissing(byIndex, int(first.frameHeader.GlobalMeta.TotalFrames)) {
+		return nil, false
+	}
+
+	samples, rate, err := audio.ReadTrack(fr.AudioTrack)
+	if err == nil {
+		var pkt audio.Packet
+		if pkt, err = audio.Decode(samples, rate); err == nil {
+			switch pkt.Kind {
+			case audio.KindManifest:
+				if !needFirst {
+					return nil, false
+				}
+				var res decodeResult
+				if res, err = manifestFrame(pkt.Data); err == nil {
+					fmt.Println("🔊 Frame 0 restaurado da faixa de áudio")
+					return &res, false
+				}
+			case audio.KindPayload:
+				if err = os.WriteFile(outputPath, pkt.Data, 0644); err == nil {
+					fmt.Println("🔊 Payload completo recuperado da faixa de áudio")
+					return nil, true
+				}
+			default:
+				err = fmt.Errorf("pacote de %s desconhecido", audio.KindName(pkt.Kind))
+			}
+		}
+	}
+	fmt.Printf("ℹ️  Sem recuperação pela faixa de áudio: %v\n", err)
+	return nil, false
+}
+
+// framesMissing: Algum índice em [0, total) sem frame decodificado
+func framesMissing(byIndex map[int]decodeResult, total int) bool {
+	for i := 0; i < total; i++ {
+		if _, ok := byIndex[i]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// manifestFrame: Frame 0 a partir do manifest do áudio (FrameHeader +
+// GlobalHeader + dados), validado pelo próprio CRC do header
+func manifestFrame(b []byte) (decodeResult, error) {
+	header, err := encoder.DecodeHeader(b)
+	if err != nil {
+		return decodeResult{}, err
+	}
+	frameData := b[encoder.FrameHeaderSizeBytes:]
+	if string(header.Magic[:3]) != "NCC" || header.FrameIndex != 0 || header.HasGlobal != 1 ||
+		int(header.DataSize) != len(frameData) || crc32.ChecksumIEEE(frameData) != header.DataCRC {
+		return decodeResult{}, fmt.Errorf("manifest da faixa de áudio inválido")
+	}
+	gh, err := encoder.DecodeGlobalHeader(frameData)
+	if err != nil {
+		return decodeResult{}, err
+	}
+	header.GlobalMeta = gh
+	return decodeResult{
+		name:        "faixa de áudio",
+		data:        frameData[encoder.GlobalHeaderSizeBytes:],
+		frameHeader: header,
+		crcOK:       true,
+	}, nil
+}
@@ -0,0 +1,333 @@
+package decoder
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Parâmetros da localização do vídeo em capturas de tela/câmera
+const (
+	captureWindowDiv  = 40   // Janela do desvio padrão local = largura / captureWindowDiv
+	captureMinArea    = 0.05 // Área mínima do vídeo (fração da captura)
+	captureSideLines  = 48   // Linhas de varredura por lado no refinamento
+	captureSideSmooth = 3    // Pixels (de cada lado) somados ao longo do lado
+	captureLineTol    = 1.5  // Distância máxima (pixels) de um ponto à reta do lado
+)
+
+// capturePlanes: Localiza o vídeo dentro da captura (tela gravada ou foto
+// da tela), corrige a perspectiva e devolve o frame reamostrado no tamanho
+// do encoder. O frame inteiro é área de dados (barra + grade), então o
+// retângulo do vídeo é a região de alto contraste local; os lados são
+// refinados na resolução da captura.
+func (fr *FrameReconstructor) capturePlanes(lp *lumaPlane) ([]*lumaPlane, error) {
+	corners, err := locateCapture(lp)
+	if err != nil {
+		return nil, err
+	}
+	w, h := float64(fr.FrameCfg.Width), float64(fr.FrameCfg.Height)
+	ref := [4]point{{0, 0}, {w, 0}, {0, h}, {w, h}}
+	return fr.orientedPlanes(lp, ref, corners)
+}
+
+// locateCapture: Cantos (TL, TR, BL, BR) do vídeo na captura
+func locateCapture(lp *lumaPlane) ([4]point, error) {
+	// Desvio padrão local em uma grade grossa (janela de alguns macro
+	// pixels): células binárias aleatórias ficam perto de metade do
+	// contraste, fundo liso só tem ruído do sensor
+	r := max(4, lp.W/captureWindowDiv)
+	step := max(2, r/2)
+	gw, gh := lp.W/step, lp.H/step
+	if gw < 4 || gh < 4 {
+		return [4]point{}, fmt.Errorf("captura muito pequena (%dx%d)", lp.W, lp.H)
+	}
+	sq := squaredIntegral(lp)
+	deviation := make([]float64, gw*gh)
+	for gy := 0; gy < gh; gy++ {
+		for gx := 0; gx < gw; gx++ {
+			cx, cy := gx*step+step/2, gy*step+step/2
+			deviation[gy*gw+gx] = localDeviation(lp, sq, cx-r, cy-r, cx+r+1, cy+r+1)
+		}
+	}
+
+	// Vídeo ocupa boa parte da captura: metade do percentil 90 separa a
+	// grade de dados do fundo
+	sorted := append([]float64(nil), deviation...)
+	sort.Float64s(sorted)
+	cut := sorted[len(sorted)*9/10] / 2
+	if cut < 1 {
+		return [4]point{}, fmt.Errorf("nenhuma região de dados na captura")
+	}
+
+	cells := largestComponent(deviation, gw, gh, cut)
+	if float64(len(cells)) < captureMinArea*float64(gw*gh) {
+		return [4]point{}, fmt.Errorf("região de dados muito pequena na captura")
+	}
+
+	// Contraste preto/branco da grade (exposição desta captura): desvio de
+	// uma distribuição binária equilibrada é metade do contraste
+	inside := make([]float64, len(cells))
+	for i, c := range cells {
+		inside[i] = deviation[c]
+	}
+	sort.Float64s(inside)
+	edgeThreshold := 2 * inside[len(inside)/2] / 3
+
+	// Cantos grossos: extremos nas diagonais (vídeo aproximadamente de pé)
+	var coarse [4]point
+	var best [4]float64
+	for i := range best {
+		best[i] = math.Inf(-1)
+	}
+	for _, c := range cells {
+		p := point{float64(c%gw*step + step/2), float64(c/gw*step + step/2)}
+		scores := [4]float64{-(p.X + p.Y), p.X - p.Y, p.Y - p.X, p.X + p.Y}
+		for i, s := range scores {
+			if s > best[i] {
+				best[i], coarse[i] = s, p
+			}
+		}
+	}
+
+	// Lados (TL-TR, TR-BR, BR-BL, BL-TL) refinados pela primeira borda vinda
+	// de fora; cantos finais = interseção dos lados ajustados
+	tl, tr, bl, br := coarse[0], coarse[1], coarse[2], coarse[3]
+	sides := [4][2]point{{tl, tr}, {tr, br}, {br, bl}, {bl, tl}}
+	var lines [4]line
+	for i, s := range sides {
+		l, ok := refineSide(lp, s[0], s[1], float64(2*r), edgeThreshold)
+		if !ok {
+			return [4]point{}, fmt.Errorf("borda do vídeo não encontrada (lado %d)", i)
+		}
+		lines[i] = l
+	}
+
+	var corners [4]point
+	pairs := [4][2]int{{3, 0}, {0, 1}, {2, 3}, {1, 2}} // TL, TR, BL, BR
+	for i, p := range pairs {
+		c, ok := lines[p[0]].intersect(lines[p[1]])
+		if !ok {
+			return [4]point{}, fmt.Errorf("lados do vídeo paralelos")
+		}
+		corners[i] = c
+	}
+	return corners, nil
+}
+
+// squaredIntegral: Imagem integral dos quadrados (variância por janela)
+func squaredIntegral(lp *lumaPlane) []uint64 {
+	stride := lp.W + 1
+	sq := make([]uint64, stride*(lp.H+1))
+	for y := 0; y < lp.H; y++ {
+		var row uint64
+		for x := 0; x < lp.W; x++ {
+			v := uint64(lp.Pix[y*lp.W+x])
+			row += v * v
+			sq[(y+1)*stride+x+1] = sq[y*stride+x+1] + row
+		}
+	}
+	return sq
+}
+
+// localDeviation: Desvio padrão da luminância no retângulo (meio aberto)
+func localDeviation(lp *lumaPlane, sq []uint64, x0, y0, x1, y1 int) float64 {
+	x0, y0 = max(x0, 0), max(y0, 0)
+	x1, y1 = min(x1, lp.W), min(y1, lp.H)
+	sum, count := lp.rectSum(x0, y0, x1, y1)
+	if count == 0 {
+		return 0
+	}
+	stride := lp.W + 1
+	sumSq := sq[y1*stride+x1] - sq[y0*stride+x1] - sq[y1*stride+x0] + sq[y0*stride+x0]
+	mean := float64(sum) / float64(count)
+	return math.Sqrt(math.Max(0, float64(sumSq)/float64(count)-mean*mean))
+}
+
+// largestComponent: Maior região 4-conexa com valor acima do corte
+func largestComponent(values []float64, gw, gh int, cut float64) []int {
+	seen := make([]bool, len(values))
+	var best, queue []int
+	for start := range values {
+		if seen[start] || values[start] < cut {
+			continue
+		}
+		queue = append(queue[:0], start)
+		seen[start] = true
+		for head := 0; head < len(queue); head++ {
+			c := queue[head]
+			x, y := c%gw, c/gw
+			for _, n := range [4][2]int{{x - 1, y}, {x + 1, y}, {x, y - 1}, {x, y + 1}} {
+				if n[0] < 0 || n[1] < 0 || n[0] >= gw || n[1] >= gh {
+					continue
+				}
+				ni := n[1]*gw + n[0]
+				if !seen[ni] && values[ni] >= cut {
+					seen[ni] = true
+					queue = append(queue, ni)
+				}
+			}
+		}
+		if len(queue) > len(best) {
+			best = append(best[:0], queue...)
+		}
+	}
+	return best
+}
+
+// line: Reta a*x + b*y = c com (a, b) unitário
+type line struct {
+	A, B, C float64
+}
+
+func lineThrough(p, q point) line {
+	a, b := q.Y-p.Y, p.X-q.X
+	n := math.Hypot(a, b)
+	return line{a / n, b / n, (a*p.X + b*p.Y) / n}
+}
+
+func (l line) distance(p point) float64 {
+	return math.Abs(l.A*p.X + l.B*p.Y - l.C)
+}
+
+func (l line) intersect(o line) (point, bool) {
+	det := l.A*o.B - o.A*l.B
+	if math.Abs(det) < 1e-9 {
+		return point{}, false
+	}
+	return point{(l.C*o.B - o.C*l.B) / det, (l.A*o.C - o.A*l.C) / det}, true
+}
+
+// refineSide: Varre linhas perpendiculares ao lado grosso a-b, de fora
+// (reach pixels além) para dentro, marcando a primeira borda forte. Onde o
+// macro pixel da borda tem a cor do fundo a borda aparece mais para dentro,
+// e o fundo pode ter texturas: a reta com mais pontos alinhados (busca
+// exaustiva por pares) é o lado real, refinada por mínimos quadrados.
+func refineSide(lp *lumaPlane, a, b point, reach, threshold float64) (line, bool) {
+	dx, dy := b.X-a.X, b.Y-a.Y
+	length := math.Hypot(dx, dy)
+	if length < 1 {
+		return line{}, false
+	}
+	tx, ty := dx/length, dy/length
+	// Normal para fora: cantos em ordem horária, fora fica à esquerda
+	nx, ny := ty, -tx
+
+	n := int(2 * reach)
+	profile := make([]float64, n)
+	var pts []point
+	for k := 0; k < captureSideLines; k++ {
+		t := 0.1 + 0.8*float64(k)/float64(captureSideLines-1)
+		px, py := a.X+dx*t, a.Y+dy*t
+
+		// Perfil de fora para dentro, média ao longo do lado (ruído do sensor)
+		for i := range profile {
+			s := reach - float64(i)
+			var sum float64
+			for j := -captureSideSmooth; j <= captureSideSmooth; j++ {
+				x := px + nx*s + tx*float64(j)
+				y := py + ny*s + ty*float64(j)
+				sum += float64(lp.bilinear(x-0.5, y-0.5))
+			}
+			profile[i] = sum / (2*captureSideSmooth + 1)
+		}
+
+		// Primeira diferença central forte; pico com ajuste parabólico
+		diff := func(i int) float64 { return math.Abs(profile[i+1] - profile[i-1]) }
+		for i := 1; i < n-1; i++ {
+			if diff(i) <= threshold {
+				continue
+			}
+			for i+2 < n-1 && diff(i+1) > diff(i) {
+				i++
+			}
+			offset := 0.0
+			if i+1 < n-1 {
+				l, c, r := diff(i-1), diff(i), diff(i+1)
+				if den := l - 2*c + r; den < 0 {
+					offset = 0.5 * (l - r) / den
+				}
+			}
+			s := reach - (float64(i) + offset)
+			pts = append(pts, point{px + nx*s, py + ny*s})
+			break
+		}
+	}
+	if len(pts) < 4 {
+		return line{}, false
+	}
+
+	// Retas candidatas: pares de pontos, seus alinhados e quantos pontos
+	// ficam fora (além da reta, no sentido da normal)
+	type candidate struct {
+		inliers []point
+		outside int
+	}
+	var cands []candidate
+	most := 0
+	for i := 0; i < len(pts); i++ {
+		for j := i + 1; j < len(pts); j++ {
+			if dist(pts[i], pts[j]) < length*0.2 {
+				continue
+			}
+			l := lineThrough(pts[i], pts[j])
+			// Orientar a normal da reta para fora
+			sign := 1.0
+			if l.A*nx+l.B*ny < 0 {
+				sign = -1
+			}
+			var c candidate
+			for _, p := range pts {
+				d := sign * (l.A*p.X + l.B*p.Y - l.C)
+				if math.Abs(d) <= captureLineTol {
+					c.inliers = append(c.inliers, p)
+				} else if d > 0 {
+					c.outside++
+				}
+			}
+			cands = append(cands, c)
+			most = max(most, len(c.inliers))
+		}
+	}
+
+	// Lado real: nenhuma borda do frame fica além dele. A reta com mais
+	// pontos pode ser a primeira linha de macro pixels quando a borda do
+	// frame é quase toda da cor do fundo (ex.: padding zerado no último
+	// frame); pontos além dela denunciam. Texturas do fundo contam igual
+	// para todas as candidatas.
+	minSupport := max(4, most/4, len(pts)/8)
+	var chosen *candidate
+	for i := range cands {
+		c := &cands[i]
+		if len(c.inliers) < minSupport {
+			continue
+		}
+		if chosen == nil || c.outside < chosen.outside ||
+			(c.outside == chosen.outside && len(c.inliers) > len(chosen.inliers)) {
+			chosen = c
+		}
+	}
+	if chosen == nil {
+		return line{}, false
+	}
+	return fitLine(chosen.inliers), true
+}
+
+// fitLine: Mínimos quadrados totais (eixo principal dos pontos)
+func fitLine(pts []point) line {
+	var mx, my float64
+	for _, p := range pts {
+		mx += p.X
+		my += p.Y
+	}
+	n := float64(len(pts))
+	mx, my = mx/n, my/n
+	var sxx, syy, sxy float64
+	for _, p := range pts {
+		sxx += (p.X - mx) * (p.X - mx)
+		syy += (p.Y - my) * (p.Y - my)
+		sxy += (p.X - mx) * (p.Y - my)
+	}
+	theta := 0.5 * math.Atan2(2*sxy, sxx-syy) // Direção da reta
+	a, b := -math.Sin(theta), math.Cos(theta)
+	return line{a, b, a*mx + b*my}
+}
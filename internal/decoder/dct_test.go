@@ -0,0 +1,70 @@
+package decoder
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+
+	"ncc/internal/encoder"
+)
+
+// dctTestFrame: Frame 0 renderizado em modulação DCT (cinza, 1 byte/pixel)
+func dctTestFrame(t *testing.T, data []byte) (encoder.FrameConfig, []byte, []byte) {
+	t.Helper()
+	cfg := encoder.DCTFrameConfig()
+	ecc, err := encoder.NewECCEncoder(encoder.NewECCConfig("low"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := encoder.NewFrame(cfg, ecc, 0, data, 1, 0, [32]byte{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := f.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pix := make([]byte, cfg.Width*cfg.Height)
+	if err := encoder.NewFrameRenderer(cfg).RenderGray(pix, stream); err != nil {
+		t.Fatal(err)
+	}
+	return cfg, stream, pix
+}
+
+// TestDCTBlockRoundTrip: Blocos renderizados pelo encoder são lidos bit a
+// bit por readDCT
+func TestDCTBlockRoundTrip(t *testing.T) {
+	data := make([]byte, 2000)
+	rand.New(rand.NewSource(4)).Read(data)
+	cfg, stream, pix := dctTestFrame(t, data)
+
+	fr := NewFrameReconstructor("dct")
+	lp := newLumaPlaneFromPix(cfg.Width, cfg.Height, pix)
+	bits := readDCT(lp, fr.detectGeometry(lp))
+	if n := cfg.GridBytes(); !bytes.Equal(bits[:n], stream[:n]) {
+		t.Fatal("bits lidos diferem do stream renderizado")
+	}
+}
+
+// TestDCTFrameGainOffset: Frame DCT com ganho, offset e ruído (o sinal dos
+// coeficientes não depende deles) decodifica com CRC válido
+func TestDCTFrameGainOffset(t *testing.T) {
+	data := make([]byte, 2000)
+	rand.New(rand.NewSource(5)).Read(data)
+	cfg, _, pix := dctTestFrame(t, data)
+
+	rng := rand.New(rand.NewSource(6))
+	for i, p := range pix {
+		v := 0.7*float64(p) + 40 + rng.NormFloat64()*4
+		pix[i] = uint8(math.Max(0, math.Min(255, math.Round(v))))
+	}
+	fr := NewFrameReconstructor("dct")
+	res := fr.decodeDCTPlane(newLumaPlaneFromPix(cfg.Width, cfg.Height, pix))
+	if !res.verified() {
+		t.Fatalf("frame não verificado: %v", res.err)
+	}
+	if !bytes.Equal(res.data, data) {
+		t.Errorf("dados diferem: %d bytes, want %d", len(res.data), len(data))
+	}
+}
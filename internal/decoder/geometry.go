@@ -0,0 +1,118 @@
+package decoder
+
+import (
+	"math"
+
+	"ncc/internal/encoder"
+)
+
+// Tolerância ao comparar a escala vertical com a altura medida da barra
+const scaleTolerance = 0.15
+
+// gridGeometry: Mapeamento da grade de macro pixels para pixels da imagem
+// decodificada. Permite ler renditions 480p/720p/1440p do mesmo upload e
+// grades detectadas com pitch arbitrário (fracionário).
+type gridGeometry struct {
+	ScaleX, ScaleY   float64 // Pixels da imagem por pixel do encoder
+	PitchX, PitchY   float64 // Lado do macro pixel em pixels da imagem
+	OriginX, OriginY float64 // Canto superior esquerdo da grade (pixels da imagem)
+	Cols, Rows       int
+}
+
+// baseGeometry: Grade do FrameConfig escalada para a imagem
+func (fr *FrameReconstructor) baseGeometry(scaleX, scaleY float64) gridGeometry {
+	cols, rows := fr.FrameCfg.GridSize()
+	macroSize := float64(fr.FrameCfg.MacroSize)
+	return gridGeometry{
+		ScaleX:  scaleX,
+		ScaleY:  scaleY,
+		PitchX:  macroSize * scaleX,
+		PitchY:  macroSize * scaleY,
+		OriginX: 0,
+		OriginY: float64(encoder.CalibrationBarHeight) * scaleY,
+		Cols:    cols,
+		Rows:    rows,
+	}
+}
+
+// shifted: Mesma grade deslocada (offsets de recuperação, pixels da imagem)
+func (g gridGeometry) shifted(dx, dy int) gridGeometry {
+	g.OriginX += float64(dx)
+	g.OriginY += float64(dy)
+	return g
+}
+
+// exact: Grade alinhada a pixels inteiros na resolução do encoder
+func (g gridGeometry) exact() bool {
+	return g.ScaleX == 1 && g.ScaleY == 1 &&
+		g.PitchX == math.Trunc(g.PitchX) && g.PitchY == math.Trunc(g.PitchY) &&
+		g.OriginX == math.Trunc(g.OriginX) && g.OriginY == math.Trunc(g.OriginY)
+}
+
+// macroSize: Lado do macro pixel em pixels do encoder
+func (g gridGeometry) macroSize() float64 {
+	return g.PitchX / g.ScaleX
+}
+
+// barHeight: Altura da barra de calibração em pixels da imagem
+func (g gridGeometry) barHeight() int {
+	h := int(math.Round(float64(encoder.CalibrationBarHeight) * g.ScaleY))
+	if h < 1 {
+		h = 1
+	}
+	return h
+}
+
+// detectGeometry: Estima a escala a partir das dimensões da imagem e valida
+// a escala vertical pela altura medida da barra de calibração (vídeos com
+// barras pretas/cortes mantêm a proporção horizontal).
+func (fr *FrameReconstructor) detectGeometry(lp *lumaPlane) gridGeometry {
+	if lp.W == fr.FrameCfg.Width && lp.H == fr.FrameCfg.Height {
+		return fr.baseGeometry(1, 1)
+	}
+
+	scaleX := float64(lp.W) / float64(fr.FrameCfg.Width)
+	scaleY := float64(lp.H) / float64(fr.FrameCfg.Height)
+
+	if measured := measureBarHeight(lp); measured > 0 {
+		barScale := float64(measured) / float64(encoder.CalibrationBarHeight)
+		if math.Abs(barScale-scaleY) > scaleY*scaleTolerance &&
+			math.Abs(barScale-scaleX) <= scaleX*scaleTolerance {
+			scaleY = scaleX
+		}
+	}
+	return fr.baseGeometry(scaleX, scaleY)
+}
+
+// measureBarHeight: Conta as linhas do topo que mantêm o padrão
+// preto/branco/preto/branco da barra de calibração. Retorna 0 se não
+// encontrar a barra.
+func measureBarHeight(lp *lumaPlane) int {
+	section := lp.W / 4
+	if section < 4 {
+		return 0
+	}
+
+	// Amostrar o centro de cada seção (evita bordas borradas)
+	xs := [4]int{section / 2, section + section/2, 2*section + section/2, 3*section + section/2}
+	limit := lp.H / 4
+	for y := 0; y < limit; y++ {
+		var l [4]int
+		for i, x := range xs {
+			l[i] = int(lp.at(x, y))
+		}
+		// Seções brancas (1, 3) devem ser claramente mais claras que as pretas (0, 2)
+		minWhite := l[1]
+		if l[3] < minWhite {
+			minWhite = l[3]
+		}
+		maxBlack := l[0]
+		if l[2] > maxBlack {
+			maxBlack = l[2]
+		}
+		if minWhite-maxBlack < 64 {
+			return y
+		}
+	}
+	return 0
+}
@@ -0,0 +1,162 @@
+package decoder
+
+import "math"
+
+// Limites da detecção de grade (pixels da imagem)
+const (
+	gridMinPitch   = 3.0
+	gridMaxPitch   = 128.0
+	gridMinCorr    = 0.1  // Autocorrelação mínima para aceitar periodicidade
+	gridHarmonic   = 0.5  // Fração do pico para preferir o sub-harmônico
+	gridPitchStep  = 0.01 // Resolução do refinamento de pitch
+	gridPhaseStep  = 0.25 // Resolução da fase
+	gridRowStride  = 2    // Subamostragem de linhas/colunas nos perfis
+	gridMinSymbols = 64   // Grade mínima plausível (macro pixels)
+)
+
+// detectGrid: Estima pitch e fase da grade de macro pixels a partir da
+// própria imagem. Perfis de gradiente de luminância (bordas entre macro
+// pixels) são analisados por autocorrelação e refinados por um ajuste de
+// pente, suportando tamanhos arbitrários e pitch fracionário (renditions
+// escaladas). base fornece escala e origem esperadas.
+func detectGrid(lp *lumaPlane, base gridGeometry) (gridGeometry, bool) {
+	w, h := lp.W, lp.H
+	top := base.barHeight()
+	if w < 16 || h-top < 16 {
+		return base, false
+	}
+
+	luma := func(x, y int) float64 {
+		return float64(lp.Pix[y*w+x])
+	}
+
+	// Perfil de bordas verticais (colunas) e horizontais (linhas), apenas na
+	// área de dados abaixo da barra de calibração
+	colProfile := make([]float64, w-1)
+	for y := top; y < h; y += gridRowStride {
+		prev := luma(0, y)
+		for x := 0; x < w-1; x++ {
+			cur := luma(x+1, y)
+			colProfile[x] += math.Abs(cur - prev)
+			prev = cur
+		}
+	}
+	rowProfile := make([]float64, h-1)
+	for x := 0; x < w; x += gridRowStride {
+		prev := luma(x, top)
+		for y := top; y < h-1; y++ {
+			cur := luma(x, y+1)
+			rowProfile[y] += math.Abs(cur - prev)
+			prev = cur
+		}
+	}
+
+	pitchX, phaseX, okX := estimatePeriod(colProfile)
+	pitchY, phaseY, okY := estimatePeriod(rowProfile[top:])
+	if !okX || !okY {
+		return base, false
+	}
+
+	// Bordas no índice i ficam entre os pixels i e i+1: a fronteira do macro
+	// pixel está em i+1. Escolher a origem mais próxima da esperada.
+	geo := base
+	geo.PitchX, geo.PitchY = pitchX, pitchY
+	geo.OriginX = nearestOrigin(phaseX+1, pitchX, base.OriginX)
+	geo.OriginY = nearestOrigin(float64(top)+phaseY+1, pitchY, base.OriginY)
+	geo.Cols = int((float64(w)-geo.OriginX)/pitchX + 0.01)
+	geo.Rows = int((float64(h)-geo.OriginY)/pitchY + 0.01)
+	if geo.Cols*geo.Rows < gridMinSymbols {
+		return base, false
+	}
+	return geo, true
+}
+
+// estimatePeriod: Período e fase dominantes de um perfil de bordas
+func estimatePeriod(profile []float64) (pitch, phase float64, ok bool) {
+	n := len(profile)
+	maxLag := int(math.Min(gridMaxPitch, float64(n)/3))
+	minLag := int(gridMinPitch)
+	if maxLag <= minLag {
+		return 0, 0, false
+	}
+
+	var mean float64
+	for _, v := range profile {
+		mean += v
+	}
+	mean /= float64(n)
+	d := make([]float64, n)
+	var energy float64
+	for i, v := range profile {
+		d[i] = v - mean
+		energy += d[i] * d[i]
+	}
+	if energy == 0 {
+		return 0, 0, false
+	}
+
+	corr := make([]float64, maxLag+1)
+	best := minLag
+	for lag := minLag; lag <= maxLag; lag++ {
+		var s float64
+		for i := 0; i+lag < n; i++ {
+			s += d[i] * d[i+lag]
+		}
+		corr[lag] = s / energy
+		if corr[lag] > corr[best] {
+			best = lag
+		}
+	}
+	if corr[best] < gridMinCorr {
+		return 0, 0, false
+	}
+
+	// Múltiplos do período também correlacionam: preferir o menor divisor
+	// com correlação comparável
+	for div := best / minLag; div >= 2; div-- {
+		sub := float64(best) / float64(div)
+		lo, hi := int(math.Floor(sub)), int(math.Ceil(sub))
+		if lo < minLag {
+			continue
+		}
+		if math.Max(corr[lo], corr[hi]) >= corr[best]*gridHarmonic {
+			best = int(math.Round(sub))
+			if float64(best) != sub {
+				// Pitch fracionário: refinar a partir do valor exato
+				return refinePeriod(d, sub)
+			}
+			break
+		}
+	}
+	return refinePeriod(d, float64(best))
+}
+
+// refinePeriod: Ajuste de pente (pitch fracionário + fase) em torno de guess
+func refinePeriod(d []float64, guess float64) (pitch, phase float64, ok bool) {
+	n := float64(len(d))
+	bestScore := math.Inf(-1)
+	for p := math.Max(gridMinPitch, guess-1); p <= guess+1; p += gridPitchStep {
+		for ph := 0.0; ph < p; ph += gridPhaseStep {
+			var s float64
+			var count int
+			for pos := ph; pos < n-1; pos += p {
+				i := int(pos)
+				a := pos - float64(i)
+				s += d[i]*(1-a) + d[i+1]*a
+				count++
+			}
+			if count == 0 {
+				continue
+			}
+			if score := s / float64(count); score > bestScore {
+				bestScore, pitch, phase = score, p, ph
+			}
+		}
+	}
+	return pitch, phase, bestScore > 0
+}
+
+// nearestOrigin: Representante de pos (módulo pitch) mais próximo de expected
+func nearestOrigin(pos, pitch, expected float64) float64 {
+	return pos - pitch*math.Round((pos-expected)/pitch)
+}
@@ -0,0 +1,71 @@
+package decoder
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// gridTestPlane: Imagem sintética com macro pixels de pitch e origem
+// arbitrários (fora dos presets) abaixo de uma barra de calibração
+func gridTestPlane(w, h int, pitch, originX, originY float64, top int) *lumaPlane {
+	rng := rand.New(rand.NewSource(1))
+	levels := map[[2]int]uint8{}
+	pix := make([]uint8, w*h)
+	for y := top; y < h; y++ {
+		for x := 0; x < w; x++ {
+			cell := [2]int{
+				int(math.Floor((float64(x) - originX) / pitch)),
+				int(math.Floor((float64(y) - originY) / pitch)),
+			}
+			v, ok := levels[cell]
+			if !ok {
+				v = uint8(rng.Intn(2) * 255)
+				levels[cell] = v
+			}
+			pix[y*w+x] = v
+		}
+	}
+	return newLumaPlaneFromPix(w, h, pix)
+}
+
+func TestDetectGrid(t *testing.T) {
+	const pitch, originX, originY = 7.3, 3.4, 18.6
+	base := gridGeometry{ScaleX: 1, ScaleY: 1, PitchX: 8, PitchY: 8, OriginX: 0, OriginY: 16}
+	lp := gridTestPlane(480, 360, pitch, originX, originY, base.barHeight())
+
+	geo, ok := detectGrid(lp, base)
+	if !ok {
+		t.Fatal("grade não detectada")
+	}
+	if math.Abs(geo.PitchX-pitch) > 0.05 || math.Abs(geo.PitchY-pitch) > 0.05 {
+		t.Errorf("pitch = %.2fx%.2f, want %.2f", geo.PitchX, geo.PitchY, pitch)
+	}
+	// A origem só é definida módulo o pitch
+	phaseErr := func(got, want float64) float64 {
+		d := math.Mod(got-want, geo.PitchX)
+		return math.Min(math.Abs(d), geo.PitchX-math.Abs(d))
+	}
+	if phaseErr(geo.OriginX, originX) > 0.5 || phaseErr(geo.OriginY, originY) > 0.5 {
+		t.Errorf("origem = (%.2f, %.2f), want (%.2f, %.2f)", geo.OriginX, geo.OriginY, originX, originY)
+	}
+}
+
+func TestDetectedGridRetries(t *testing.T) {
+	base := gridGeometry{ScaleX: 1, ScaleY: 1, PitchX: 8, PitchY: 8, OriginX: 0, OriginY: 16}
+	fr := &FrameReconstructor{}
+
+	// Frame em branco: sem detecção, e a falha não fica em cache
+	blank := newLumaPlaneFromPix(480, 360, make([]uint8, 480*360))
+	if _, ok := fr.detectedGrid(blank, base); ok {
+		t.Fatal("grade detectada em frame em branco")
+	}
+	lp := gridTestPlane(480, 360, 7.3, 3.4, 18.6, base.barHeight())
+	grid, ok := fr.detectedGrid(lp, base)
+	if !ok {
+		t.Fatal("detecção não repetida após frame em branco")
+	}
+	if cached, ok := fr.detectedGrid(blank, base); !ok || cached != grid {
+		t.Error("detecção bem-sucedida não ficou em cache")
+	}
+}
@@ -0,0 +1,124 @@
+package decoder
+
+import "math"
+
+// levelMapTile: Lado do tile (em macro pixels) usado na estimativa local
+const levelMapTile = 12
+
+// levelMap: Limiares de decisão por macro pixel. Estimados por tile a partir
+// das estatísticas locais e interpolados bilinearmente entre os centros dos
+// tiles, compensando vinheta, deriva DC do codec e mudanças de gamma.
+type levelMap struct {
+	cols, rows int
+	cells      [][3]uint8 // Limiares por macro pixel (ordem raster)
+}
+
+// uniformLevelMap: Mesmo limiar para toda a grade (calibração global)
+func uniformLevelMap(cols, rows int, threshold byte, thresholds [3]uint8, grayLevels int) *levelMap {
+	t := thresholds
+	if grayLevels == 2 {
+		t = [3]uint8{threshold, threshold, threshold}
+	}
+	cells := make([][3]uint8, cols*rows)
+	for i := range cells {
+		cells[i] = t
+	}
+	return &levelMap{cols: cols, rows: rows, cells: cells}
+}
+
+// buildLevelMap: Estima limiares por tile; tiles ambíguos herdam os limiares
+// globais (fallback).
+func buildLevelMap(samples []uint8, cols, rows, grayLevels int, fallback *levelMap) *levelMap {
+	if cols == 0 || rows == 0 || len(samples) < cols*rows {
+		return fallback
+	}
+
+	tilesX := (cols + levelMapTile - 1) / levelMapTile
+	tilesY := (rows + levelMapTile - 1) / levelMapTile
+	global := fallback.cells[0]
+
+	nt := grayLevels - 1
+	if grayLevels == 2 {
+		nt = 1
+	}
+
+	tiles := make([][3]float64, tilesX*tilesY)
+	found := 0
+	buf := make([]uint8, 0, levelMapTile*levelMapTile)
+	for ty := 0; ty < tilesY; ty++ {
+		for tx := 0; tx < tilesX; tx++ {
+			buf = buf[:0]
+			for r := ty * levelMapTile; r < (ty+1)*levelMapTile && r < rows; r++ {
+				for c := tx * levelMapTile; c < (tx+1)*levelMapTile && c < cols; c++ {
+					buf = append(buf, samples[r*cols+c])
+				}
+			}
+
+			t := &tiles[ty*tilesX+tx]
+			if est, ok := estimateLevels(buf, grayLevels); ok {
+				found++
+				if grayLevels == 2 {
+					t[0] = float64(est.Threshold)
+				} else {
+					for i := 0; i < nt; i++ {
+						t[i] = float64(est.Thresholds[i])
+					}
+				}
+			} else {
+				for i := 0; i < nt; i++ {
+					t[i] = float64(global[i])
+				}
+			}
+		}
+	}
+	if found == 0 {
+		return fallback
+	}
+
+	// Interpolação bilinear entre centros de tiles
+	lm := &levelMap{cols: cols, rows: rows, cells: make([][3]uint8, cols*rows)}
+	for r := 0; r < rows; r++ {
+		fy := clampFloat((float64(r)+0.5)/levelMapTile-0.5, 0, float64(tilesY-1))
+		y0 := int(fy)
+		y1 := min(y0+1, tilesY-1)
+		ay := fy - float64(y0)
+		for c := 0; c < cols; c++ {
+			fx := clampFloat((float64(c)+0.5)/levelMapTile-0.5, 0, float64(tilesX-1))
+			x0 := int(fx)
+			x1 := min(x0+1, tilesX-1)
+			ax := fx - float64(x0)
+
+			a, b := tiles[y0*tilesX+x0], tiles[y0*tilesX+x1]
+			d, e := tiles[y1*tilesX+x0], tiles[y1*tilesX+x1]
+			var cell [3]uint8
+			for i := 0; i < nt; i++ {
+				top := a[i]*(1-ax) + b[i]*ax
+				bottom := d[i]*(1-ax) + e[i]*ax
+				cell[i] = uint8(math.Round(top*(1-ay) + bottom*ay))
+			}
+			if grayLevels == 2 {
+				cell[1], cell[2] = cell[0], cell[0]
+			}
+			lm.cells[r*cols+c] = cell
+		}
+	}
+	return lm
+}
+
+// at: Limiares do macro pixel i (ordem raster)
+func (lm *levelMap) at(i int) [3]uint8 {
+	if i < len(lm.cells) {
+		return lm.cells[i]
+	}
+	return lm.cells[len(lm.cells)-1]
+}
+
+func clampFloat(v, lo, hi float64) float64 {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
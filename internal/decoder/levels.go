@@ -0,0 +1,151 @@
+package decoder
+
+import "math"
+
+// Parâmetros da estimativa de níveis
+const (
+	levelMaxIterations = 24  // Iterações máximas do k-means
+	levelMinGap        = 12  // Distância mínima entre centros adjacentes
+	levelSeparation    = 1.2 // Gap mínimo em unidades de (σa + σb)
+	levelValleyRatio   = 0.6 // Vale deve ser < 60% do menor pico vizinho
+)
+
+// levelEstimate: Níveis de cinza estimados a partir do conteúdo do frame
+type levelEstimate struct {
+	Centers    []float64 // Centro de cada nível (ordem crescente)
+	Threshold  uint8     // Limiar binário (2 níveis)
+	Thresholds [3]uint8  // Limiares 4-níveis (0/1, 1/2, 2/3)
+}
+
+// estimateLevels: Agrupa as médias dos macro pixels em k níveis (k-means 1D
+// sobre o histograma) e posiciona cada limiar no vale entre clusters vizinhos.
+// Retorna ok=false quando a separação é ambígua; o chamador deve então usar
+// a barra de calibração.
+func estimateLevels(samples []uint8, k int) (levelEstimate, bool) {
+	var est levelEstimate
+	if k < 2 || len(samples) < k*16 {
+		return est, false
+	}
+
+	var hist [256]float64
+	for _, s := range samples {
+		hist[s]++
+	}
+	total := float64(len(samples))
+
+	// Inicialização por quantis: payload cifrado/padding aleatório distribui
+	// os símbolos de forma aproximadamente uniforme entre os níveis
+	centers := make([]float64, k)
+	var acc float64
+	next := 0
+	for v := 0; v < 256 && next < k; v++ {
+		acc += hist[v]
+		for next < k && acc >= total*(float64(next)+0.5)/float64(k) {
+			centers[next] = float64(v)
+			next++
+		}
+	}
+
+	// Lloyd sobre o histograma (custo O(256·k) por iteração)
+	counts := make([]float64, k)
+	sums := make([]float64, k)
+	sqSums := make([]float64, k)
+	for iter := 0; iter < levelMaxIterations; iter++ {
+		for i := range counts {
+			counts[i], sums[i], sqSums[i] = 0, 0, 0
+		}
+		for v := 0; v < 256; v++ {
+			if hist[v] == 0 {
+				continue
+			}
+			c := nearestCenter(centers, float64(v))
+			counts[c] += hist[v]
+			sums[c] += hist[v] * float64(v)
+			sqSums[c] += hist[v] * float64(v) * float64(v)
+		}
+		moved := false
+		for i := range centers {
+			if counts[i] == 0 {
+				continue
+			}
+			mean := sums[i] / counts[i]
+			if math.Abs(mean-centers[i]) > 0.01 {
+				moved = true
+			}
+			centers[i] = mean
+		}
+		if !moved {
+			break
+		}
+	}
+
+	// Validação: população mínima por cluster
+	minShare := total / float64(4*k)
+	sigmas := make([]float64, k)
+	for i := range centers {
+		if counts[i] < minShare {
+			return est, false
+		}
+		variance := sqSums[i]/counts[i] - centers[i]*centers[i]
+		if variance < 0 {
+			variance = 0
+		}
+		sigmas[i] = math.Sqrt(variance)
+	}
+
+	// Histograma suavizado para localizar vales
+	var smooth [256]float64
+	for v := 0; v < 256; v++ {
+		var s float64
+		for d := -2; d <= 2; d++ {
+			if u := v + d; u >= 0 && u < 256 {
+				s += hist[u]
+			}
+		}
+		smooth[v] = s / 5
+	}
+
+	thresholds := make([]uint8, k-1)
+	for i := 0; i < k-1; i++ {
+		lo, hi := centers[i], centers[i+1]
+		gap := hi - lo
+		if gap < levelMinGap || gap < levelSeparation*(sigmas[i]+sigmas[i+1]) {
+			return est, false
+		}
+
+		loIdx, hiIdx := int(math.Round(lo)), int(math.Round(hi))
+		valley := (loIdx + hiIdx + 1) / 2
+		for v := loIdx + 1; v < hiIdx; v++ {
+			if smooth[v] < smooth[valley] {
+				valley = v
+			}
+		}
+		peak := math.Min(smooth[loIdx], smooth[hiIdx])
+		if peak > 0 && smooth[valley] > peak*levelValleyRatio {
+			return est, false
+		}
+		thresholds[i] = uint8(valley)
+	}
+
+	est.Centers = centers
+	if k == 2 {
+		est.Threshold = thresholds[0]
+		est.Thresholds = [3]uint8{thresholds[0], thresholds[0], thresholds[0]}
+	} else {
+		est.Threshold = thresholds[(k-1)/2]
+		copy(est.Thresholds[:], thresholds)
+	}
+	return est, true
+}
+
+// nearestCenter: Índice do centro mais próximo de v
+func nearestCenter(centers []float64, v float64) int {
+	best := 0
+	bestDist := math.Abs(v - centers[0])
+	for i := 1; i < len(centers); i++ {
+		if d := math.Abs(v - centers[i]); d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
@@ -0,0 +1,129 @@
+package decoder
+
+import (
+	"image"
+)
+
+// lumaPlane: Plano de luminância contíguo (1 byte/pixel) com imagem integral.
+// Substitui img.At(...).RGBA() no loop quente: a média de qualquer retângulo
+// (macro pixel, seção da barra) custa O(1).
+type lumaPlane struct {
+	W, H int
+	Pix  []uint8 // Linhas contíguas (stride = W)
+
+	// Somas acumuladas (W+1)x(H+1). Aritmética modular em uint32: a soma de
+	// um retângulo continua exata enquanto couber em 32 bits (até ~16M pixels).
+	integral []uint32
+
+	// Reamostrado por homografia (scan/captura): bordas dos macro pixels
+	// não são nítidas mesmo na escala 1:1
+	resampled bool
+}
+
+// newLumaPlane: Converte a imagem em plano Y. Caminhos rápidos para
+// *image.Gray (saída gray do FFmpeg), *image.YCbCr (plano Y direto),
+// *image.RGBA e *image.NRGBA; demais tipos passam por img.At.
+func newLumaPlane(img image.Image) *lumaPlane {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	lp := &lumaPlane{W: w, H: h}
+
+	switch src := img.(type) {
+	case *image.Gray:
+		if src.Stride == w {
+			lp.Pix = src.Pix[:w*h]
+		} else {
+			lp.Pix = make([]uint8, w*h)
+			for y := 0; y < h; y++ {
+				off := src.PixOffset(b.Min.X, b.Min.Y+y)
+				copy(lp.Pix[y*w:(y+1)*w], src.Pix[off:off+w])
+			}
+		}
+	case *image.YCbCr:
+		lp.Pix = make([]uint8, w*h)
+		for y := 0; y < h; y++ {
+			off := src.YOffset(b.Min.X, b.Min.Y+y)
+			copy(lp.Pix[y*w:(y+1)*w], src.Y[off:off+w])
+		}
+	case *image.RGBA:
+		lp.Pix = make([]uint8, w*h)
+		for y := 0; y < h; y++ {
+			row := src.Pix[src.PixOffset(b.Min.X, b.Min.Y+y):]
+			for x := 0; x < w; x++ {
+				lp.Pix[y*w+x] = rgbToLuma(row[x*4], row[x*4+1], row[x*4+2])
+			}
+		}
+	case *image.NRGBA:
+		lp.Pix = make([]uint8, w*h)
+		for y := 0; y < h; y++ {
+			row := src.Pix[src.PixOffset(b.Min.X, b.Min.Y+y):]
+			for x := 0; x < w; x++ {
+				lp.Pix[y*w+x] = rgbToLuma(row[x*4], row[x*4+1], row[x*4+2])
+			}
+		}
+	default:
+		lp.Pix = make([]uint8, w*h)
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				r, g, bl, _ := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+				lp.Pix[y*w+x] = rgbToLuma(uint8(r>>8), uint8(g>>8), uint8(bl>>8))
+			}
+		}
+	}
+
+	lp.buildIntegral()
+	return lp
+}
+
+// newLumaPlaneFromPix: Plano a partir de um buffer Y já contíguo (rawvideo gray)
+func newLumaPlaneFromPix(w, h int, pix []uint8) *lumaPlane {
+	lp := &lumaPlane{W: w, H: h, Pix: pix[:w*h]}
+	lp.buildIntegral()
+	return lp
+}
+
+// rgbToLuma: Y BT.601 em inteiros (frames em cinza: R == G == B == Y)
+func rgbToLuma(r, g, b uint8) uint8 {
+	return uint8((299*uint32(r) + 587*uint32(g) + 114*uint32(b) + 500) / 1000)
+}
+
+func (lp *lumaPlane) buildIntegral() {
+	stride := lp.W + 1
+	lp.integral = make([]uint32, stride*(lp.H+1))
+	for y := 0; y < lp.H; y++ {
+		var rowSum uint32
+		row := lp.Pix[y*lp.W : (y+1)*lp.W]
+		above := lp.integral[y*stride : (y+1)*stride]
+		cur := lp.integral[(y+1)*stride : (y+2)*stride]
+		for x, v := range row {
+			rowSum += uint32(v)
+			cur[x+1] = above[x+1] + rowSum
+		}
+	}
+}
+
+// Bounds: Retângulo do plano (origem em 0,0)
+func (lp *lumaPlane) Bounds() image.Rectangle {
+	return image.Rect(0, 0, lp.W, lp.H)
+}
+
+// at: Luminância do pixel (0 fora do plano)
+func (lp *lumaPlane) at(x, y int) uint8 {
+	if x < 0 || y < 0 || x >= lp.W || y >= lp.H {
+		return 0
+	}
+	return lp.Pix[y*lp.W+x]
+}
+
+// rectSum: Soma e contagem de pixels em [x0,x1) x [y0,y1), recortado ao plano
+func (lp *lumaPlane) rectSum(x0, y0, x1, y1 int) (uint32, int) {
+	x0, y0 = max(x0, 0), max(y0, 0)
+	x1, y1 = min(x1, lp.W), min(y1, lp.H)
+	if x1 <= x0 || y1 <= y0 {
+		return 0, 0
+	}
+	stride := lp.W + 1
+	sum := lp.integral[y1*stride+x1] - lp.integral[y0*stride+x1] -
+		lp.integral[y1*stride+x0] + lp.integral[y0*stride+x0]
+	return sum, (x1 - x0) * (y1 - y0)
+}
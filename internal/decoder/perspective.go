@@ -0,0 +1,118 @@
+package decoder
+
+import "math"
+
+// point: Coordenada em pixels (subpixel)
+type point struct {
+	X, Y float64
+}
+
+// homography: Transformação projetiva 3x3 (linha a linha, h[8] = 1)
+type homography [9]float64
+
+// solveHomography: Homografia que leva src[i] em dst[i] (4 pares, DLT com
+// eliminação de Gauss). ok = false para pontos degenerados (colineares).
+func solveHomography(src, dst [4]point) (homography, bool) {
+	var a [8][9]float64
+	for i := 0; i < 4; i++ {
+		x, y := src[i].X, src[i].Y
+		u, v := dst[i].X, dst[i].Y
+		a[2*i] = [9]float64{x, y, 1, 0, 0, 0, -u * x, -u * y, u}
+		a[2*i+1] = [9]float64{0, 0, 0, x, y, 1, -v * x, -v * y, v}
+	}
+
+	// Eliminação com pivotamento parcial
+	for col := 0; col < 8; col++ {
+		pivot := col
+		for r := col + 1; r < 8; r++ {
+			if math.Abs(a[r][col]) > math.Abs(a[pivot][col]) {
+				pivot = r
+			}
+		}
+		if math.Abs(a[pivot][col]) < 1e-12 {
+			return homography{}, false
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		for r := 0; r < 8; r++ {
+			if r == col {
+				continue
+			}
+			f := a[r][col] / a[col][col]
+			for c := col; c < 9; c++ {
+				a[r][c] -= f * a[col][c]
+			}
+		}
+	}
+
+	var h homography
+	for i := 0; i < 8; i++ {
+		h[i] = a[i][8] / a[i][i]
+	}
+	h[8] = 1
+	return h, true
+}
+
+// apply: Projeta (x, y)
+func (h homography) apply(x, y float64) (float64, float64) {
+	w := h[6]*x + h[7]*y + h[8]
+	return (h[0]*x + h[1]*y + h[2]) / w, (h[3]*x + h[4]*y + h[5]) / w
+}
+
+// warpPlane: Reamostra src em um plano w x h. m leva coordenadas do
+// destino (centro do pixel) para a imagem de origem; interpolação bilinear.
+// Quando cada pixel do destino cobre vários da origem (tela filmada de
+// perto), usa a média da área coberta: a grade de subpixels do monitor
+// viraria moiré na amostragem pontual.
+func warpPlane(src *lumaPlane, m homography, w, h int) *lumaPlane {
+	r := int(warpFootprint(m, w, h) / 2)
+	pix := make([]uint8, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			sx, sy := m.apply(float64(x)+0.5, float64(y)+0.5)
+			if r == 0 {
+				pix[y*w+x] = src.bilinear(sx-0.5, sy-0.5)
+				continue
+			}
+			cx, cy := int(sx), int(sy)
+			sum, count := src.rectSum(cx-r, cy-r, cx+r+1, cy+r+1)
+			if count == 0 {
+				continue
+			}
+			pix[y*w+x] = uint8((sum + uint32(count)/2) / uint32(count))
+		}
+	}
+	lp := newLumaPlaneFromPix(w, h, pix)
+	lp.resampled = true
+	return lp
+}
+
+// warpFootprint: Lado médio (pixels da origem) de um pixel do destino
+func warpFootprint(m homography, w, h int) float64 {
+	var q [4]point
+	for i, c := range [4][2]float64{{0, 0}, {float64(w), 0}, {float64(w), float64(h)}, {0, float64(h)}} {
+		q[i].X, q[i].Y = m.apply(c[0], c[1])
+	}
+	// Área do quadrilátero (fórmula do laço)
+	var area float64
+	for i := range q {
+		j := (i + 1) % 4
+		area += q[i].X*q[j].Y - q[j].X*q[i].Y
+	}
+	return math.Sqrt(math.Abs(area) / 2 / float64(w*h))
+}
+
+// bilinear: Luminância interpolada (bordas replicadas)
+func (lp *lumaPlane) bilinear(x, y float64) uint8 {
+	x = clampFloat(x, 0, float64(lp.W-1))
+	y = clampFloat(y, 0, float64(lp.H-1))
+	x0, y0 := int(x), int(y)
+	x1, y1 := min(x0+1, lp.W-1), min(y0+1, lp.H-1)
+	ax, ay := x-float64(x0), y-float64(y0)
+
+	p00 := float64(lp.Pix[y0*lp.W+x0])
+	p10 := float64(lp.Pix[y0*lp.W+x1])
+	p01 := float64(lp.Pix[y1*lp.W+x0])
+	p11 := float64(lp.Pix[y1*lp.W+x1])
+	v := (p00*(1-ax)+p10*ax)*(1-ay) + (p01*(1-ax)+p11*ax)*ay
+	return uint8(v + 0.5)
+}
@@ -1,570 +1,923 @@
-package decoder
-
-import (
-	"bytes"
-	"crypto/sha256"
-	"fmt"
-	"hash/crc32"
-	"image"
-	_ "image/png"
-	"os"
-	"runtime"
-	"sort"
-	"sync"
-	"sync/atomic"
-
-	"ncc/internal/encoder"
-)
-
-type FrameReconstructor struct {
-	FrameCfg encoder.FrameConfig
-	ECCCfg   encoder.ECCConfig
-}
-
-func NewFrameReconstructor(preset string) *FrameReconstructor {
-	cfg := encoder.DefaultFrameConfig()
-	if preset == "youtube" {
-		cfg = encoder.YouTubeFrameConfig()
-	} else if preset == "dense" {
-		cfg = encoder.HighDensityFrameConfig()
-	}
-
-	return &FrameReconstructor{
-		FrameCfg: cfg,
-		ECCCfg:   encoder.ECCConfig{DataShards: 16, ParityShards: 48}, // Padrão/Legado
-	}
-}
-
-type decodeResult struct {
-	index       int
-	data        []byte
-	frameHeader encoder.FrameHeader
-	crcOK       bool
-	err         error
-}
-
-func (fr *FrameReconstructor) ReconstructFile(framePaths []string, outputPath string, progress chan<- float64) error {
-	var allData []byte
-	var globalHeader *encoder.GlobalHeader
-	var crcWarnings int32 // Atomic
-
-	// Determinar threads: Deixar 2 livres
-	threads := runtime.NumCPU() - 2
-	if threads < 1 {
-		threads = 1
-	}
-	fmt.Printf("🚀 Usando %d threads para reconstrução (deixando 2 livres)\n", threads)
-
-	// Ordenar caminhos
-	sort.Slice(framePaths, func(i, j int) bool {
-		return framePaths[i] < framePaths[j]
-	})
-
-	// Canais
-	jobChan := make(chan struct {
-		i    int
-		path string
-	}, len(framePaths))
-	resultChan := make(chan decodeResult, len(framePaths))
-
-	// Workers
-	var wg sync.WaitGroup
-	for i := 0; i < threads; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for job := range jobChan {
-				data, header, crcOK, err := fr.processFrame(job.path)
-				resultChan <- decodeResult{
-					index:       job.i,
-					data:        data,
-					frameHeader: header,
-					crcOK:       crcOK,
-					err:         err,
-				}
-			}
-		}()
-	}
-
-	// Despachar jobs
-	for i, path := range framePaths {
-		jobChan <- struct {
-			i    int
-			path string
-		}{i, path}
-	}
-	close(jobChan)
-
-	// Aguardar workers e fechar canal
-	go func() {
-		wg.Wait()
-		close(resultChan)
-	}()
-
-	// Coletar resultados
-	resultsMap := make(map[int]decodeResult)
-	var processed int
-
-	for res := range resultChan {
-		if res.err != nil {
-			return fmt.Errorf("frame process error: %w", res.err)
-		}
-		if !res.crcOK {
-			atomic.AddInt32(&crcWarnings, 1)
-			fmt.Fprintf(os.Stderr, "⚠️  WARNING: Frame %d CRC mismatch (corrected)\n", res.index)
-		}
-
-		resultsMap[res.index] = res
-
-		processed++
-		if progress != nil {
-			// Reportar progresso (decodificação é pesada)
-			progress <- float64(processed) / float64(len(framePaths))
-		}
-	}
-
-	// Montagem Sequencial
-	fmt.Println("📦 Montando arquivo final...")
-	for i := 0; i < len(framePaths); i++ {
-		res, ok := resultsMap[i]
-		if !ok {
-			return fmt.Errorf("missing result for frame %d", i)
-		}
-
-		if i == 0 {
-			globalHeader = &res.frameHeader.GlobalMeta
-		}
-		allData = append(allData, res.data...)
-	}
-
-	if crcWarnings > 0 {
-		fmt.Fprintf(os.Stderr, "\n⚠️  Total CRC warnings: %d/%d frames\n", crcWarnings, len(framePaths))
-	}
-
-	if globalHeader != nil {
-		if len(framePaths) != int(globalHeader.TotalFrames) {
-			fmt.Fprintf(os.Stderr, "⚠️  Warning: expected %d frames, found %d\n",
-				globalHeader.TotalFrames, len(framePaths))
-		}
-	}
-
-	// Tamanho original é ofuscado (0) no Header.
-	// Confiamos no FrameHeader.DataSize.
-
-	// SEGURANÇA: Verificação SHA-256 é feita após descriptografia (no main)
-	// Hash está no payload criptografado.
-	fmt.Println("✅ Arquivo reconstruído com sucesso")
-
-	return os.WriteFile(outputPath, allData, 0644)
-}
-
-func verifySHA256(data []byte, expected []byte) bool {
-	hash := sha256.Sum256(data)
-	return bytes.Equal(hash[:], expected)
-}
-
-// processFrame com RECUPERAÇÃO UNIVERSAL (Tamanho + Espacial + Níveis)
-func (fr *FrameReconstructor) processFrame(path string) ([]byte, encoder.FrameHeader, bool, error) {
-	var emptyHeader encoder.FrameHeader
-
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, emptyHeader, false, err
-	}
-	defer f.Close()
-
-	img, _, err := image.Decode(f)
-	if err != nil {
-		return nil, emptyHeader, false, fmt.Errorf("decode png: %w", err)
-	}
-
-	// ✅ Detecção Automática de Resolução
-	bounds := img.Bounds()
-	if bounds.Dx() != fr.FrameCfg.Width || bounds.Dy() != fr.FrameCfg.Height {
-		fmt.Printf("⚠️  Resolução diferente: Config=%dx%d, Imagem=%dx%d. Ajustando.\n",
-			fr.FrameCfg.Width, fr.FrameCfg.Height, bounds.Dx(), bounds.Dy())
-		fr.FrameCfg.Width = bounds.Dx()
-		fr.FrameCfg.Height = bounds.Dy()
-	}
-
-	// Tentar calibração
-	threshold, err := fr.calibrateFrame(img)
-	if err != nil {
-		fmt.Printf("Warning: calibration failed for frame: %v\n", err)
-		threshold = 128 // Fallback
-	}
-
-	levels, err := fr.calibrateLevels(img)
-	if err != nil {
-		levels = [3]uint8{64, 128, 192} // Fallback
-	}
-
-	// Leitura Inicial
-	allBytes, err := fr.readBytesFromImage(img, threshold, levels, 0, 0)
-	if err != nil {
-		return nil, emptyHeader, false, err
-	}
-
-	// Verificar Magic
-	if len(allBytes) >= encoder.FrameHeaderSizeBytes {
-		headerProbe, _ := encoder.DecodeHeader(allBytes[:encoder.FrameHeaderSizeBytes])
-		if headerProbe.Magic != [4]byte{'N', 'C', 'C', '1'} {
-			fmt.Printf("⚠️  Invalid Magic (%v). Starting Universal Recovery...\n", headerProbe.Magic)
-
-			found := false
-			originalSize := fr.FrameCfg.MacroSize
-
-			// 3. Scan Espacial e de Tamanho (Recuperação Avançada)
-			// Tamanhos: 10, 12, 16, 24, 8, 32
-			// Offsets: -3 a +3
-			testSizes := []int{10, 12, 16, 24, 8, 32}
-
-			for _, size := range testSizes {
-				fr.FrameCfg.MacroSize = size
-
-				offsets := []int{0, 1, -1, 2, -2, 3, -3}
-
-				for _, offY := range offsets {
-					for _, offX := range offsets {
-						probeBytes, _ := fr.readBytesFromImage(img, threshold, levels, offX, offY)
-						if len(probeBytes) < encoder.FrameHeaderSizeBytes {
-							continue
-						}
-
-						h, _ := encoder.DecodeHeader(probeBytes[:encoder.FrameHeaderSizeBytes])
-						if h.Magic == [4]byte{'N', 'C', 'C', '1'} {
-							fmt.Printf("✅ Recovery SUCCESS! Size: %d px, Offset: (%d, %d)\n", size, offX, offY)
-							allBytes = probeBytes
-							found = true
-							// Corrigir offset no futuro?
-							// Idealmente armazenaríamos offsets, mas scan por frame é mais seguro.
-							goto RecoveryDone
-						}
-					}
-				}
-			}
-
-			// Se falhar, tentar Recuperação de Nível Profundo
-			if !found {
-				fr.FrameCfg.MacroSize = originalSize
-				fmt.Printf("⚠️  Scan espacial falhou. Tentando Recuperação de Nível...\n")
-			} else {
-				goto RecoveryDone
-			}
-
-			// 4. Scan de Nível e Ganho
-
-			if fr.FrameCfg.GrayLevels == 2 {
-				for t := 30; t < 220; t += 5 {
-					if t == int(threshold) {
-						continue
-					}
-					probeBytes, _ := fr.readBytesFromImage(img, byte(t), levels, 0, 0)
-					if len(probeBytes) < encoder.FrameHeaderSizeBytes {
-						continue
-					}
-					h, _ := encoder.DecodeHeader(probeBytes[:encoder.FrameHeaderSizeBytes])
-					if h.Magic == [4]byte{'N', 'C', 'C', '1'} {
-						fmt.Printf("✅ Recovery SUCCESS at threshold %d!\n", t)
-						allBytes = probeBytes
-						found = true
-						break
-					}
-				}
-			} else {
-				baseT2 := int(levels[1])
-				baseRange := int(levels[2]) - int(levels[0])
-				if baseRange < 20 {
-					baseRange = 100
-				}
-
-				for centerShift := -60; centerShift <= 60; centerShift += 5 {
-					for rangeScale := 0.5; rangeScale <= 1.5; rangeScale += 0.1 {
-						newCenter := baseT2 + centerShift
-						newRange := float64(baseRange) * rangeScale
-
-						t2 := newCenter
-						t1 := int(float64(t2) - newRange*0.35)
-						t3 := int(float64(t2) + newRange*0.35)
-
-						if t1 < 0 {
-							t1 = 0
-						}
-						if t2 < t1 {
-							t2 = t1 + 5
-						}
-						if t3 < t2 {
-							t3 = t2 + 5
-						}
-						if t3 > 255 {
-							t3 = 255
-						}
-
-						newLevels := [3]uint8{uint8(t1), uint8(t2), uint8(t3)}
-
-						probeBytes, _ := fr.readBytesFromImage(img, threshold, newLevels, 0, 0)
-						if len(probeBytes) < encoder.FrameHeaderSizeBytes {
-							continue
-						}
-						h, _ := encoder.DecodeHeader(probeBytes[:encoder.FrameHeaderSizeBytes])
-						if h.Magic == [4]byte{'N', 'C', 'C', '1'} {
-							fmt.Printf("✅ Recovery SUCCESS! Shift=%d, Scale=%.1f. Levels: %v\n", centerShift, rangeScale, newLevels)
-							levels = newLevels
-							allBytes = probeBytes
-							found = true
-							break
-						}
-					}
-					if found {
-						break
-					}
-				}
-			}
-
-		RecoveryDone:
-			if !found {
-				fmt.Println("❌ Recovery failed. Header corrupted.")
-			}
-		}
-	}
-
-	if len(allBytes) < encoder.FrameHeaderSizeBytes {
-		return nil, emptyHeader, false, fmt.Errorf("frame too small: %d bytes", len(allBytes))
-	}
-
-	header, err := encoder.DecodeHeader(allBytes[:encoder.FrameHeaderSizeBytes])
-	if err != nil {
-		return nil, emptyHeader, false, fmt.Errorf("decode header: %w", err)
-	}
-
-	if header.Magic != [4]byte{'N', 'C', 'C', '1'} {
-		return nil, emptyHeader, false, fmt.Errorf("invalid magic: %v (expected NCC1)", header.Magic)
-	}
-
-	// Calcular bytes por frame
-	cols, rows := fr.FrameCfg.GridSize()
-	totalMacros := cols * rows
-	var bytesInFrame int
-	if fr.FrameCfg.GrayLevels == 2 {
-		bytesInFrame = totalMacros / 8
-	} else {
-		bytesInFrame = totalMacros / 4
-	}
-	usableBytes := bytesInFrame - encoder.FrameHeaderSizeBytes
-	if usableBytes > len(allBytes)-encoder.FrameHeaderSizeBytes {
-		usableBytes = len(allBytes) - encoder.FrameHeaderSizeBytes
-	}
-	dataWithECC := allBytes[encoder.FrameHeaderSizeBytes : encoder.FrameHeaderSizeBytes+usableBytes]
-
-	// Determinar config ECC do header
-	parityShards := int(header.ParityShards)
-	if parityShards == 0 {
-		parityShards = 48 // Padrão legado
-	}
-	eccCfg := encoder.ECCConfig{
-		DataShards:   16,
-		ParityShards: parityShards,
-	}
-
-	ecc, err := encoder.NewECCEncoder(eccCfg)
-	if err != nil {
-		return nil, emptyHeader, false, fmt.Errorf("create ECC: %w", err)
-	}
-
-	totalShards := eccCfg.DataShards + eccCfg.ParityShards
-	shardSize := (int(header.DataSize) + eccCfg.DataShards - 1) / eccCfg.DataShards
-	if shardSize == 0 {
-		shardSize = 1
-	}
-
-	eccBytes := shardSize * totalShards
-	if eccBytes > len(dataWithECC) {
-		eccBytes = len(dataWithECC)
-	}
-	dataWithECC = dataWithECC[:eccBytes]
-
-	// Dividir em shards com segurança de zero-padding
-	shards := make([][]byte, totalShards)
-	for i := range shards {
-		start := i * shardSize
-		end := start + shardSize
-
-		var shardData []byte
-
-		if start >= len(dataWithECC) {
-			// Se fora dos dados, shard zerado
-			shardData = make([]byte, shardSize)
-		} else {
-			if end > len(dataWithECC) {
-				end = len(dataWithECC)
-			}
-			// Copiar dados disponíveis
-			shardData = make([]byte, shardSize)
-			copy(shardData, dataWithECC[start:end])
-		}
-		shards[i] = shardData
-	}
-
-	ok, _ := ecc.Verify(shards)
-	if !ok {
-		if err := ecc.Reconstruct(shards); err != nil {
-			return nil, emptyHeader, false, fmt.Errorf("reconstruct failed: %w", err)
-		}
-	}
-
-	expectedSize := fr.ECCCfg.DataShards * shardSize
-	out, err := ecc.Join(shards, expectedSize)
-	if err != nil {
-		return nil, emptyHeader, false, fmt.Errorf("join failed: %w", err)
-	}
-
-	var actualData []byte
-	crcOK := true
-
-	dataLen := int(header.DataSize)
-	if dataLen > len(out) {
-		dataLen = len(out)
-	}
-
-	if header.HasGlobal == 1 && header.FrameIndex == 0 {
-		if len(out) < encoder.GlobalHeaderSizeBytes {
-			return nil, emptyHeader, false, fmt.Errorf("insufficient data for GlobalHeader: %d bytes", len(out))
-		}
-
-		gh, err := encoder.DecodeGlobalHeader(out[:encoder.GlobalHeaderSizeBytes])
-		if err != nil {
-			return nil, emptyHeader, false, fmt.Errorf("decode GlobalHeader: %w", err)
-		}
-
-		header.GlobalMeta = gh
-		frameData := out[:dataLen]
-		if crc32.ChecksumIEEE(frameData) != header.DataCRC {
-			crcOK = false
-		}
-		actualData = out[encoder.GlobalHeaderSizeBytes:dataLen]
-	} else {
-		actualData = out[:dataLen]
-		if crc32.ChecksumIEEE(actualData) != header.DataCRC {
-			crcOK = false
-		}
-	}
-
-	return actualData, header, crcOK, nil
-}
-
-func (fr *FrameReconstructor) calibrateFrame(img image.Image) (byte, error) {
-	bounds := img.Bounds()
-	width := bounds.Dx()
-	sectionWidth := width / 4
-	sampleY := encoder.CalibrationBarHeight / 2
-	blackAvg := fr.measureSectionAverage(img, 0, sampleY, sectionWidth, encoder.CalibrationBarHeight)
-	whiteAvg := fr.measureSectionAverage(img, 3*sectionWidth, sampleY, sectionWidth, encoder.CalibrationBarHeight)
-	threshold := uint8((int(blackAvg) + int(whiteAvg)) / 2)
-	return byte(threshold), nil
-}
-
-func (fr *FrameReconstructor) calibrateLevels(img image.Image) ([3]uint8, error) {
-	bounds := img.Bounds()
-	width := bounds.Dx()
-	sectionWidth := width / 4
-	sampleY := encoder.CalibrationBarHeight / 2
-	blackAvg := float64(fr.measureSectionAverage(img, 0, sampleY, sectionWidth, encoder.CalibrationBarHeight))
-	whiteAvg := float64(fr.measureSectionAverage(img, 3*sectionWidth, sampleY, sectionWidth, encoder.CalibrationBarHeight))
-	rng := whiteAvg - blackAvg
-	if rng < 10 { // Safety check
-		return [3]uint8{64, 128, 192}, nil
-	}
-	t1 := uint8(blackAvg + rng*(1.0/6.0))
-	t2 := uint8(blackAvg + rng*(0.5))
-	t3 := uint8(blackAvg + rng*(5.0/6.0))
-	return [3]uint8{t1, t2, t3}, nil
-}
-
-func (fr *FrameReconstructor) measureSectionAverage(img image.Image, startX, startY, w, h int) uint8 {
-	var sum uint32
-	var count uint32
-	marginX := w / 4
-	marginY := h / 4
-	for y := startY + marginY; y < startY+h-marginY; y++ {
-		for x := startX + marginX; x < startX+w-marginX; x++ {
-			r, _, _, _ := img.At(x, y).RGBA()
-			sum += r >> 8
-			count++
-		}
-	}
-	if count == 0 {
-		return 128
-	}
-	return uint8(sum / count)
-}
-
-func (fr *FrameReconstructor) extractMacroPixel(img image.Image, startX, startY int) (y, u, v uint8) {
-	var sumR uint32
-	realY := startY + encoder.CalibrationBarHeight
-	bounds := img.Bounds()
-	macroSize := fr.FrameCfg.MacroSize
-
-	count := 0
-	for dy := 0; dy < macroSize; dy++ {
-		for dx := 0; dx < macroSize; dx++ {
-			px := startX + dx
-			py := realY + dy
-			if px >= bounds.Dx() || py >= bounds.Dy() {
-				continue
-			}
-			r, _, _, _ := img.At(bounds.Min.X+px, bounds.Min.Y+py).RGBA()
-			sumR += r >> 8
-			count++
-		}
-	}
-
-	if count == 0 {
-		return 0, 128, 128
-	}
-	avgR := uint8(sumR / uint32(count))
-	return avgR, 128, 128
-}
-
-// readBytesFromImage com suporte a offset
-func (fr *FrameReconstructor) readBytesFromImage(img image.Image, threshold byte, thresholds [3]uint8, offX, offY int) ([]byte, error) {
-	cols, rows := fr.FrameCfg.GridSize()
-	macroSize := fr.FrameCfg.MacroSize
-
-	var bits []byte
-	for y := 0; y < rows; y++ {
-		for x := 0; x < cols; x++ {
-			// Adicionar offsets
-			targetX := x*macroSize + offX
-			targetY := y*macroSize + offY
-
-			avgY, _, _ := fr.extractMacroPixel(img, targetX, targetY)
-
-			var val byte
-			if fr.FrameCfg.GrayLevels == 2 {
-				if avgY >= threshold {
-					val = 1
-				} else {
-					val = 0
-				}
-			} else {
-				val = encoder.DynGrayToNibble(avgY, thresholds)
-			}
-			bits = append(bits, val)
-		}
-	}
-
-	var allBytes []byte
-	if fr.FrameCfg.GrayLevels == 2 {
-		for i := 0; i+7 < len(bits); i += 8 {
-			b := (bits[i] << 7) | (bits[i+1] << 6) | (bits[i+2] << 5) | (bits[i+3] << 4) |
-				(bits[i+4] << 3) | (bits[i+5] << 2) | (bits[i+6] << 1) | bits[i+7]
-			allBytes = append(allBytes, b)
-		}
-	} else {
-		for i := 0; i+3 < len(bits); i += 4 {
-			b := (bits[i] << 6) | (bits[i+1] << 4) | (bits[i+2] << 2) | bits[i+3]
-			allBytes = append(allBytes, b)
-		}
-	}
-	return allBytes, nil
-}
+package decoder
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"hash/crc32"
+	_ "image/jpeg" // Screenshots/exports
+	_ "image/png"
+	"io"
+	"math"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"ncc/internal/encoder"
+	"ncc/internal/trailer"
+)
+
+// macroMargin: Fração de cada borda ignorada ao amostrar macro pixels escalados
+const macroMargin = 0.2
+
+type FrameReconstructor struct {
+	FrameCfg encoder.FrameConfig
+	ECCCfg   encoder.ECCConfig
+	Scan     bool // Páginas impressas escaneadas/fotografadas (marcadores + perspectiva)
+	Capture  bool // Gravação da tela/câmera do vídeo tocando (localiza o vídeo no quadro)
+	// Entrada com faixa de áudio NCC (-audio no encode): repõe frame 0 ou
+	// payload ausentes no vídeo
+	AudioTrack string
+	// Frames ausentes viram lacunas zeradas (-partial) em vez de erro;
+	// os intervalos ficam em Gaps
+	Partial bool
+	Gaps    []trailer.Range
+	// Frames gerados com -scramble: o stream lido é desembaralhado antes do
+	// header (nil = frames em claro)
+	Scrambler *encoder.Scrambler
+
+	// Geometria compartilhada entre workers (travada após os primeiros
+	// frames verificados). FrameCfg é somente leitura durante a reconstrução.
+	geometry videoGeometry
+
+	frame0     *encoder.FrameHeader // Header do frame 0 (após a reconstrução)
+	frame0Grid [2]int               // Grade em que o frame 0 foi lido
+}
+
+func NewFrameReconstructor(preset string) *FrameReconstructor {
+	return &FrameReconstructor{
+		FrameCfg: encoder.PresetFrameConfig(preset),
+		ECCCfg:   encoder.ECCConfig{DataShards: 16, ParityShards: 48}, // Padrão/Legado
+	}
+}
+
+type decodeResult struct {
+	name        string
+	data        []byte
+	size        int // len(data), mantido após a escrita no arquivo
+	frameHeader encoder.FrameHeader
+	crcOK       bool
+	err         error
+	cols, rows  int // Grade em que o frame foi lido
+}
+
+// ReconstructFile: Reconstrói a partir de PNGs extraídos (ordem de nome)
+func (fr *FrameReconstructor) ReconstructFile(framePaths []string, outputPath string, progress chan<- float64) error {
+	// Ordenar caminhos
+	sort.Slice(framePaths, func(i, j int) bool {
+		return framePaths[i] < framePaths[j]
+	})
+	return fr.ReconstructSource(NewPathSource(framePaths), outputPath, progress)
+}
+
+// ReconstructSource: Lê frames da origem em streaming (memória limitada
+// pelos workers) e escreve o payload em outputPath à medida que os frames
+// chegam na ordem dos índices (sem o arquivo inteiro em RAM)
+func (fr *FrameReconstructor) ReconstructSource(src FrameSource, outputPath string, progress chan<- float64) (err error) {
+	var crcWarnings int
+
+	w, err := newPayloadWriter(outputPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := w.f.Close(); err == nil && cerr != nil {
+			err = fmt.Errorf("salvar payload: %w", cerr)
+		}
+		if err != nil {
+			os.Remove(outputPath)
+		}
+	}()
+
+	// Determinar threads: Deixar 2 livres
+	threads := runtime.NumCPU() - 2
+	if threads < 1 {
+		threads = 1
+	}
+	fmt.Printf("🚀 Usando %d threads para reconstrução (deixando 2 livres)\n", threads)
+
+	// Total conhecido de antemão (lista de arquivos, .nccv); senão vem do
+	// GlobalHeader do frame 0
+	total := 0
+	if counted, ok := src.(interface{ Len() int }); ok {
+		total = counted.Len()
+	}
+
+	// Canais (buffer limitado: frames decodificados ficam só em trânsito)
+	jobChan := make(chan FrameRef, threads*2)
+	resultChan := make(chan decodeResult, threads*2)
+	done := make(chan struct{})
+	defer close(done)
+
+	// Workers
+	var wg sync.WaitGroup
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ref := range jobChan {
+				res := fr.processFrame(ref)
+				select {
+				case resultChan <- decodeResult{
+					name:        ref.Name,
+					data:        res.data,
+					frameHeader: res.header,
+					crcOK:       res.crcOK,
+					err:         res.err,
+					cols:        res.geo.Cols,
+					rows:        res.geo.Rows,
+				}:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+
+	// Despachar jobs (leitura sequencial da origem)
+	var sourceErr error
+	var frameCount int
+	go func() {
+		defer close(jobChan)
+		for {
+			ref, err := src.Next()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				sourceErr = err
+				return
+			}
+			select {
+			case jobChan <- ref:
+				frameCount++
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	// Aguardar workers e fechar canal
+	go func() {
+		wg.Wait()
+		close(resultChan)
+	}()
+
+	// Coletar resultados por FrameIndex do header (não pela posição na
+	// origem): screenshots/exports podem vir fora de ordem ou repetidos
+	byIndex := w.frames
+	var processed, failed int
+	var writeErr error
+
+	for res := range resultChan {
+		processed++
+		if progress != nil && total > 0 {
+			// Reportar progresso (decodificação é pesada)
+			progress <- min(float64(processed)/float64(total), 1)
+		}
+
+		if res.err != nil {
+			// Frame ilegível não é fatal: a falta é reportada na montagem.
+			// Em capturas são esperados (transições entre frames, borrões).
+			failed++
+			if !fr.Capture {
+				fmt.Fprintf(os.Stderr, "⚠️  WARNING: %s ignorado: %v\n", res.name, res.err)
+			}
+			continue
+		}
+		idx := int(res.frameHeader.FrameIndex)
+		if idx == 0 && total == 0 && res.frameHeader.HasGlobal == 1 {
+			total = int(res.frameHeader.GlobalMeta.TotalFrames)
+		}
+
+		if writeErr == nil {
+			writeErr = w.add(idx, res)
+		}
+	}
+	if writeErr != nil {
+		return writeErr
+	}
+	// Despachante já terminou (jobChan fechado antes do fim dos workers)
+	if sourceErr != nil {
+		return fmt.Errorf("read frames: %w", sourceErr)
+	}
+	if frameCount == 0 {
+		return fmt.Errorf("nenhum frame encontrado na entrada")
+	}
+	if fr.Capture && failed > 0 {
+		fmt.Printf("ℹ️  %d de %d capturas ilegíveis descartadas (transições/borrões)\n", failed, frameCount)
+	}
+
+	if fr.AudioTrack != "" {
+		restored, done := fr.audioFallback(byIndex, outputPath)
+		if done {
+			return nil
+		}
+		if restored != nil {
+			if err := w.put(0, *restored); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Frame 0 carrega o GlobalHeader (total de frames)
+	first, ok := byIndex[0]
+	if !ok || first.frameHeader.HasGlobal != 1 {
+		return fmt.Errorf("frame 0 (GlobalHeader) ausente ou ilegível: total de frames desconhecido (%d lidos, %d ilegíveis)", len(byIndex), failed)
+	}
+	fr.frame0 = &first.frameHeader
+	fr.frame0Grid = [2]int{first.cols, first.rows}
+	globalHeader := &first.frameHeader.GlobalMeta
+	expected := int(globalHeader.TotalFrames)
+	if expected == 0 {
+		// Header antigo/sem contagem: assumir o maior índice visto
+		for idx := range byIndex {
+			expected = max(expected, idx+1)
+		}
+		fmt.Fprintf(os.Stderr, "⚠️  Warning: GlobalHeader sem total de frames, assumindo %d\n", expected)
+	}
+
+	var missing []int
+	for i := 0; i < expected; i++ {
+		if _, ok := byIndex[i]; !ok {
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) > 0 && fr.Partial {
+		fmt.Fprintf(os.Stderr, "⚠️  WARNING: frames ausentes: %s (%d de %d), preenchidos com zeros (-partial)\n",
+			formatIndexRanges(missing), len(missing), expected)
+		if missing[len(missing)-1] == expected-1 {
+			fmt.Fprintf(os.Stderr, "⚠️  WARNING: último frame ausente: tamanho do payload e trailer desconhecidos\n")
+		}
+	} else if len(missing) > 0 {
+		if fr.Capture {
+			// Captura mais lenta que a reprodução (ou frames sempre misturados)
+			return fmt.Errorf("frames ausentes: %s (%d de %d); grave novamente com o vídeo em velocidade menor (ex.: 0.5x)",
+				formatIndexRanges(missing), len(missing), expected)
+		}
+		return fmt.Errorf("frames ausentes: %s (%d de %d)", formatIndexRanges(missing), len(missing), expected)
+	}
+	if extra := len(byIndex) - expected; extra > 0 {
+		fmt.Fprintf(os.Stderr, "⚠️  Warning: %d frames com índice além do total (%d) ignorados\n", extra, expected)
+	}
+	if dup := frameCount - failed - len(byIndex); dup > 0 {
+		fmt.Printf("ℹ️  %d frames duplicados descartados\n", dup)
+	}
+
+	// Montagem Sequencial: frames ainda pendentes (depois de uma falta ou
+	// fora de ordem) e lacunas de -partial
+	fmt.Println("📦 Montando arquivo final...")
+	fr.Gaps = nil
+	gapSize := fr.gapSize(byIndex, expected)
+	for w.next < expected {
+		if _, ok := byIndex[w.next]; ok {
+			if err := w.flush(); err != nil {
+				return err
+			}
+			continue
+		}
+		// -partial: lacuna do tamanho de um frame cheio
+		start, err := w.gap(gapSize)
+		if err != nil {
+			return err
+		}
+		if n := len(fr.Gaps); n > 0 && fr.Gaps[n-1].End == start {
+			fr.Gaps[n-1].End = w.size
+		} else {
+			fr.Gaps = append(fr.Gaps, trailer.Range{Start: start, End: w.size})
+		}
+	}
+	for i := 0; i < expected; i++ {
+		if res, ok := byIndex[i]; ok && !res.crcOK {
+			crcWarnings++
+			fmt.Fprintf(os.Stderr, "⚠️  WARNING: Frame %d CRC mismatch (corrected)\n", i)
+		}
+	}
+
+	if crcWarnings > 0 {
+		fmt.Fprintf(os.Stderr, "\n⚠️  Total CRC warnings: %d/%d frames\n", crcWarnings, expected)
+	}
+
+	// Tamanho original é ofuscado (0) no Header.
+	// Confiamos no FrameHeader.DataSize.
+
+	// SEGURANÇA: Verificação SHA-256 é feita após descriptografia (no main)
+	// Hash está no payload criptografado.
+	if len(fr.Gaps) > 0 {
+		fmt.Printf("⚠️  Arquivo reconstruído com %d lacuna(s) zerada(s)\n", len(fr.Gaps))
+	} else {
+		fmt.Println("✅ Arquivo reconstruído com sucesso")
+	}
+
+	return nil
+}
+
+// prefixScanLimit: Frames lidos sem estender o prefixo antes de ReadPrefix
+// desistir
+const prefixScanLimit = 256
+
+// ReadPrefix: Início do payload, lido em ordem da origem até done aceitá-lo
+// (ex.: header NCC3 completo) sem reconstruir o resto. Retorna o prefixo e
+// quantos frames o compõem; usado com a chave pública do -scramble antes de
+// abrir a chave de arquivo.
+func (fr *FrameReconstructor) ReadPrefix(src FrameSource, done func([]byte) bool) ([]byte, int, error) {
+	byIndex := make(map[int][]byte)
+	var prefix []byte
+	frames, idle := 0, 0
+	for idle < prefixScanLimit {
+		ref, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("read frames: %w", err)
+		}
+		idle++
+		res := fr.processFrame(ref)
+		if res.err != nil {
+			continue
+		}
+		if idx := int(res.header.FrameIndex); idx >= frames {
+			byIndex[idx] = res.data
+		}
+		for data, ok := byIndex[frames]; ok; data, ok = byIndex[frames] {
+			prefix = append(prefix, data...)
+			delete(byIndex, frames)
+			frames++
+			idle = 0
+		}
+		if frames > 0 && done(prefix) {
+			return prefix, frames, nil
+		}
+	}
+	return nil, 0, fmt.Errorf("início do payload ilegível (%d frames lidos em ordem)", frames)
+}
+
+// gapSize: Bytes de dados de um frame cheio (todos menos o frame 0 e o
+// último): medido em um frame lido ou, sem nenhum, pela capacidade
+func (fr *FrameReconstructor) gapSize(byIndex map[int]decodeResult, expected int) int {
+	for i := 1; i < expected-1; i++ {
+		if res, ok := byIndex[i]; ok {
+			return res.size
+		}
+	}
+	parity := int(fr.frame0.ParityShards)
+	if parity == 0 {
+		parity = 48 // Padrão legado
+	}
+	return fr.FrameCfg.CapacityPerFrame(encoder.ECCConfig{DataShards: 16, ParityShards: parity}, false)
+}
+
+// Descriptor: Layout dos frames decodificados (FrameConfig + ECC do frame 0),
+// "" se o frame 0 não foi lido (ex.: payload vindo da faixa de áudio)
+func (fr *FrameReconstructor) Descriptor() string {
+	if fr.frame0 == nil {
+		return ""
+	}
+	parity := int(fr.frame0.ParityShards)
+	if parity == 0 {
+		parity = 48 // Padrão legado
+	}
+	ecc := encoder.ECCConfig{DataShards: 16, ParityShards: parity}
+	return fr.FrameCfg.GridDescriptor(fr.frame0Grid[0], fr.frame0Grid[1], ecc)
+}
+
+// Archive: ID e volume do GlobalHeader (ok = false sem frame 0)
+func (fr *FrameReconstructor) Archive() (id [6]byte, volume uint16, ok bool) {
+	if fr.frame0 == nil {
+		return id, 0, false
+	}
+	return fr.frame0.GlobalMeta.ArchiveID(), fr.frame0.GlobalMeta.Volume(), true
+}
+
+// IndexRoot: Prefixo da raiz Merkle fixado no GlobalHeader (zero sem frame
+// 0 ou sem índice)
+func (fr *FrameReconstructor) IndexRoot() [8]byte {
+	if fr.frame0 == nil {
+		return [8]byte{}
+	}
+	return fr.frame0.GlobalMeta.IndexRoot
+}
+
+// formatIndexRanges: "3, 7-9, 12" para a lista ordenada de índices
+func formatIndexRanges(indices []int) string {
+	var parts []string
+	for i := 0; i < len(indices); {
+		j := i
+		for j+1 < len(indices) && indices[j+1] == indices[j]+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, fmt.Sprintf("%d", indices[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", indices[i], indices[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ", ")
+}
+
+func verifySHA256(data []byte, expected []byte) bool {
+	hash := sha256.Sum256(data)
+	return bytes.Equal(hash[:], expected)
+}
+
+// processFrame com RECUPERAÇÃO UNIVERSAL (Grade + Espacial + Níveis).
+// A geometria travada do vídeo é apenas lida; a recuperação só sobrescreve
+// a geometria deste frame quando ele falha na verificação.
+func (fr *FrameReconstructor) processFrame(ref FrameRef) frameDecode {
+	// Plano Y contíguo + imagem integral: amostragem O(1) por macro pixel
+	lp, err := ref.load()
+	if err != nil {
+		return frameDecode{err: err}
+	}
+	if !fr.Scan && !fr.Capture {
+		return fr.decodePlane(lp)
+	}
+
+	// Página escaneada ou captura: uma leitura por orientação candidata
+	var planes []*lumaPlane
+	if fr.Scan {
+		planes, err = fr.scanPlanes(lp)
+	} else {
+		planes, err = fr.capturePlanes(lp)
+	}
+	if err != nil {
+		return frameDecode{err: fmt.Errorf("%s: %w", ref.Name, err)}
+	}
+	var firstErr error
+	for _, plane := range planes {
+		res := fr.decodePlane(plane)
+		if res.err == nil {
+			return res
+		}
+		if firstErr == nil {
+			firstErr = res.err
+		}
+	}
+	return frameDecode{err: firstErr}
+}
+
+// decodePlane: Leitura de um frame já na forma de plano Y
+func (fr *FrameReconstructor) decodePlane(lp *lumaPlane) frameDecode {
+	if fr.FrameCfg.Modulation == encoder.ModulationDCT {
+		return fr.decodeDCTPlane(lp)
+	}
+	st := fr.newFrameState(lp)
+
+	// Leitura Inicial: níveis estimados do próprio conteúdo (clusterização),
+	// refinados por região. A barra de calibração só prevalece se a
+	// clusterização for ambígua.
+	samples := fr.sampleGrid(lp, st.geo)
+	res := fr.decodeSamples(samples, st.geo, fr.estimateLevelMap(st.geo, samples, st.threshold, st.levels))
+	if res.verified() {
+		fr.confirmGeometry(lp, st.geo)
+		return res
+	}
+
+	if res.err != nil && !fr.Capture {
+		fmt.Printf("⚠️  %v. Starting Universal Recovery...\n", res.err)
+	}
+	if rec, ok := fr.recoverFrame(lp, st); ok && (rec.verified() || res.err != nil) {
+		res = rec
+		if res.verified() {
+			fr.confirmGeometry(lp, rec.geo)
+		}
+	} else if res.err != nil && !fr.Capture {
+		fmt.Println("❌ Recovery failed. Header corrupted.")
+	}
+
+	if res.err != nil {
+		return frameDecode{err: res.err}
+	}
+	return res
+}
+
+// newFrameState: Geometria travada do vídeo (se houver) ou detectada na
+// própria imagem, mais os limiares da barra de calibração
+func (fr *FrameReconstructor) newFrameState(lp *lumaPlane) frameState {
+	geo, locked := fr.geometry.lockedFor(lp.Bounds())
+	if !locked {
+		// ✅ Detecção Automática de Resolução: a grade lógica continua a do
+		// encoder; apenas o mapeamento para pixels da imagem é escalado
+		geo = fr.detectGeometry(lp)
+	}
+
+	// Tentar calibração
+	threshold, err := fr.calibrateFrame(lp, geo)
+	if err != nil {
+		fmt.Printf("Warning: calibration failed for frame: %v\n", err)
+		threshold = 128 // Fallback
+	}
+
+	levels, err := fr.calibrateLevels(lp, geo)
+	if err != nil {
+		levels = [3]uint8{64, 128, 192} // Fallback
+	}
+
+	return frameState{geo: geo, threshold: threshold, levels: levels}
+}
+
+// confirmGeometry: Voto de um frame verificado na geometria do vídeo
+func (fr *FrameReconstructor) confirmGeometry(lp *lumaPlane, geo gridGeometry) {
+	if fr.geometry.confirm(lp.Bounds(), geo) {
+		fmt.Printf("🔒 Geometria travada: macro %.2f px, grade %dx%d\n", geo.macroSize(), geo.Cols, geo.Rows)
+	}
+}
+
+// recoverFrame: Busca uma leitura verificada variando grade, posição e
+// limiares. Retorna a primeira leitura com CRC válido ou, na falta dela, a
+// primeira com header válido.
+func (fr *FrameReconstructor) recoverFrame(lp *lumaPlane, st frameState) (frameDecode, bool) {
+	var fallback *frameDecode
+
+	// 3. Grade detectada na imagem (pitch + fase, em cache após detectar), grade
+	// do preset e geometria atual, com scan espacial fino em torno de cada uma
+	// Offsets: -3 a +3
+	candidates := []gridGeometry{st.geo}
+	if base := fr.detectGeometry(lp); base != st.geo {
+		candidates = append(candidates, base)
+	}
+	if grid, ok := fr.detectedGrid(lp, st.geo); ok && grid != st.geo {
+		candidates = append([]gridGeometry{grid}, candidates...)
+	}
+	offsets := []int{0, 1, -1, 2, -2, 3, -3}
+
+	for _, cand := range candidates {
+		for _, offY := range offsets {
+			for _, offX := range offsets {
+				if cand == st.geo && offX == 0 && offY == 0 {
+					continue // Leitura inicial
+				}
+				probeGeo := cand.shifted(offX, offY)
+				probeSamples := fr.sampleGrid(lp, probeGeo)
+				probe := fr.decodeSamples(probeSamples, probeGeo, fr.estimateLevelMap(probeGeo, probeSamples, st.threshold, st.levels))
+				if probe.verified() {
+					fmt.Printf("✅ Recovery SUCCESS! Size: %.2f px, Offset: (%d, %d)\n", probeGeo.macroSize(), offX, offY)
+					return probe, true
+				}
+				if probe.err == nil && fallback == nil {
+					fallback = &probe
+				}
+			}
+		}
+	}
+
+	// 4. Scan de Nível e Ganho (mesma amostragem, limiares varridos)
+	samples := fr.sampleGrid(lp, st.geo)
+	for _, cand := range fr.levelScan(st) {
+		probe := fr.decodeSamples(samples, st.geo, cand.lm)
+		if probe.verified() {
+			fmt.Printf("✅ Recovery SUCCESS at %s!\n", cand.desc)
+			return probe, true
+		}
+		if probe.err == nil && fallback == nil {
+			fallback = &probe
+		}
+	}
+
+	if fallback != nil {
+		return *fallback, true
+	}
+	return frameDecode{}, false
+}
+
+// levelCandidate: Mapa de limiares tentado pela recuperação de nível
+type levelCandidate struct {
+	lm   *levelMap
+	desc string
+}
+
+// levelScan: Limiares globais alternativos (gamma/ganho desconhecidos)
+func (fr *FrameReconstructor) levelScan(st frameState) []levelCandidate {
+	var out []levelCandidate
+
+	if fr.FrameCfg.GrayLevels == 2 {
+		for t := 30; t < 220; t += 5 {
+			if t == int(st.threshold) {
+				continue
+			}
+			out = append(out, levelCandidate{
+				lm:   fr.uniformLevelMap(st.geo, byte(t), st.levels),
+				desc: fmt.Sprintf("threshold %d", t),
+			})
+		}
+		return out
+	}
+
+	baseT2 := int(st.levels[1])
+	baseRange := int(st.levels[2]) - int(st.levels[0])
+	if baseRange < 20 {
+		baseRange = 100
+	}
+
+	for centerShift := -60; centerShift <= 60; centerShift += 5 {
+		for rangeScale := 0.5; rangeScale <= 1.5; rangeScale += 0.1 {
+			newCenter := baseT2 + centerShift
+			newRange := float64(baseRange) * rangeScale
+
+			t2 := newCenter
+			t1 := int(float64(t2) - newRange*0.35)
+			t3 := int(float64(t2) + newRange*0.35)
+
+			if t1 < 0 {
+				t1 = 0
+			}
+			if t2 < t1 {
+				t2 = t1 + 5
+			}
+			if t3 < t2 {
+				t3 = t2 + 5
+			}
+			if t3 > 255 {
+				t3 = 255
+			}
+
+			newLevels := [3]uint8{uint8(t1), uint8(t2), uint8(t3)}
+			out = append(out, levelCandidate{
+				lm:   fr.uniformLevelMap(st.geo, st.threshold, newLevels),
+				desc: fmt.Sprintf("Shift=%d, Scale=%.1f. Levels: %v", centerShift, rangeScale, newLevels),
+			})
+		}
+	}
+	return out
+}
+
+// decodeSamples: Classifica as amostras e decodifica header + ECC
+func (fr *FrameReconstructor) decodeSamples(samples []uint8, geo gridGeometry, lm *levelMap) frameDecode {
+	allBytes := fr.samplesToBytes(samples, lm)
+	data, header, crcOK, err := fr.decodeFrameBytes(allBytes, geo)
+	return frameDecode{data: data, header: header, crcOK: crcOK, err: err, geo: geo}
+}
+
+// decodeFrameBytes: Header, shards Reed-Solomon e CRC de um frame lido
+func (fr *FrameReconstructor) decodeFrameBytes(allBytes []byte, geo gridGeometry) ([]byte, encoder.FrameHeader, bool, error) {
+	var emptyHeader encoder.FrameHeader
+
+	if len(allBytes) < encoder.FrameHeaderSizeBytes {
+		return nil, emptyHeader, false, fmt.Errorf("frame too small: %d bytes", len(allBytes))
+	}
+	if fr.Scrambler != nil {
+		allBytes = fr.Scrambler.Unscramble(allBytes)
+	}
+
+	header, err := encoder.DecodeHeader(allBytes[:encoder.FrameHeaderSizeBytes])
+	if err != nil {
+		return nil, emptyHeader, false, fmt.Errorf("decode header: %w", err)
+	}
+
+	if header.Magic != [4]byte{'N', 'C', 'C', '1'} {
+		return nil, emptyHeader, false, fmt.Errorf("invalid magic: %v (expected NCC1)", header.Magic)
+	}
+
+	// Calcular bytes por frame (grade efetivamente lida)
+	bytesInFrame := geo.Cols * geo.Rows * fr.FrameCfg.BitsPerCell() / 8
+	usableBytes := bytesInFrame - encoder.FrameHeaderSizeBytes
+	if usableBytes > len(allBytes)-encoder.FrameHeaderSizeBytes {
+		usableBytes = len(allBytes) - encoder.FrameHeaderSizeBytes
+	}
+	dataWithECC := allBytes[encoder.FrameHeaderSizeBytes : encoder.FrameHeaderSizeBytes+usableBytes]
+
+	// Determinar config ECC do header
+	parityShards := int(header.ParityShards)
+	if parityShards == 0 {
+		parityShards = 48 // Padrão legado
+	}
+	eccCfg := encoder.ECCConfig{
+		DataShards:   16,
+		ParityShards: parityShards,
+	}
+
+	ecc, err := encoder.NewECCEncoder(eccCfg)
+	if err != nil {
+		return nil, emptyHeader, false, fmt.Errorf("create ECC: %w", err)
+	}
+
+	totalShards := eccCfg.DataShards + eccCfg.ParityShards
+	shardSize := (int(header.DataSize) + eccCfg.DataShards - 1) / eccCfg.DataShards
+	if shardSize == 0 {
+		shardSize = 1
+	}
+
+	eccBytes := shardSize * totalShards
+	if eccBytes > len(dataWithECC) {
+		eccBytes = len(dataWithECC)
+	}
+	dataWithECC = dataWithECC[:eccBytes]
+
+	// Dividir em shards com segurança de zero-padding
+	shards := make([][]byte, totalShards)
+	for i := range shards {
+		start := i * shardSize
+		end := start + shardSize
+
+		var shardData []byte
+
+		if start >= len(dataWithECC) {
+			// Se fora dos dados, shard zerado
+			shardData = make([]byte, shardSize)
+		} else {
+			if end > len(dataWithECC) {
+				end = len(dataWithECC)
+			}
+			// Copiar dados disponíveis
+			shardData = make([]byte, shardSize)
+			copy(shardData, dataWithECC[start:end])
+		}
+		shards[i] = shardData
+	}
+
+	ok, _ := ecc.Verify(shards)
+	if !ok {
+		if err := ecc.Reconstruct(shards); err != nil {
+			return nil, emptyHeader, false, fmt.Errorf("reconstruct failed: %w", err)
+		}
+	}
+
+	expectedSize := fr.ECCCfg.DataShards * shardSize
+	out, err := ecc.Join(shards, expectedSize)
+	if err != nil {
+		return nil, emptyHeader, false, fmt.Errorf("join failed: %w", err)
+	}
+
+	var actualData []byte
+	crcOK := true
+
+	dataLen := int(header.DataSize)
+	if dataLen > len(out) {
+		dataLen = len(out)
+	}
+
+	if header.HasGlobal == 1 && header.FrameIndex == 0 {
+		if len(out) < encoder.GlobalHeaderSizeBytes {
+			return nil, emptyHeader, false, fmt.Errorf("insufficient data for GlobalHeader: %d bytes", len(out))
+		}
+
+		gh, err := encoder.DecodeGlobalHeader(out[:encoder.GlobalHeaderSizeBytes])
+		if err != nil {
+			return nil, emptyHeader, false, fmt.Errorf("decode GlobalHeader: %w", err)
+		}
+
+		header.GlobalMeta = gh
+		frameData := out[:dataLen]
+		if crc32.ChecksumIEEE(frameData) != header.DataCRC {
+			crcOK = false
+		}
+		actualData = out[encoder.GlobalHeaderSizeBytes:dataLen]
+	} else {
+		actualData = out[:dataLen]
+		if crc32.ChecksumIEEE(actualData) != header.DataCRC {
+			crcOK = false
+		}
+	}
+
+	return actualData, header, crcOK, nil
+}
+
+func (fr *FrameReconstructor) calibrateFrame(lp *lumaPlane, geo gridGeometry) (byte, error) {
+	sectionWidth := lp.W / 4
+	barHeight := geo.barHeight()
+	blackAvg := fr.measureSectionAverage(lp, 0, 0, sectionWidth, barHeight)
+	whiteAvg := fr.measureSectionAverage(lp, 3*sectionWidth, 0, sectionWidth, barHeight)
+	threshold := uint8((int(blackAvg) + int(whiteAvg)) / 2)
+	return byte(threshold), nil
+}
+
+func (fr *FrameReconstructor) calibrateLevels(lp *lumaPlane, geo gridGeometry) ([3]uint8, error) {
+	sectionWidth := lp.W / 4
+	barHeight := geo.barHeight()
+	blackAvg := float64(fr.measureSectionAverage(lp, 0, 0, sectionWidth, barHeight))
+	whiteAvg := float64(fr.measureSectionAverage(lp, 3*sectionWidth, 0, sectionWidth, barHeight))
+	rng := whiteAvg - blackAvg
+	if rng < 10 { // Safety check
+		return [3]uint8{64, 128, 192}, nil
+	}
+	t1 := uint8(blackAvg + rng*(1.0/6.0))
+	t2 := uint8(blackAvg + rng*(0.5))
+	t3 := uint8(blackAvg + rng*(5.0/6.0))
+	return [3]uint8{t1, t2, t3}, nil
+}
+
+func (fr *FrameReconstructor) measureSectionAverage(lp *lumaPlane, startX, startY, w, h int) uint8 {
+	marginX := w / 4
+	marginY := h / 4
+	sum, count := lp.rectSum(startX+marginX, startY+marginY, startX+w-marginX, startY+h-marginY)
+	if count == 0 {
+		return 128
+	}
+	return uint8(sum / uint32(count))
+}
+
+// extractMacroPixel: Média do macro pixel cujo retângulo (em pixels da
+// imagem, coordenadas fracionárias) começa em (startX, startY). Com margem,
+// apenas o miolo é amostrado para evitar a mistura com vizinhos nas bordas.
+func (fr *FrameReconstructor) extractMacroPixel(lp *lumaPlane, startX, startY, w, h, margin float64) uint8 {
+	marginX, marginY := w*margin, h*margin
+	x0 := int(math.Ceil(startX + marginX - 0.5))
+	x1 := int(math.Floor(startX + w - marginX - 0.5))
+	y0 := int(math.Ceil(startY + marginY - 0.5))
+	y1 := int(math.Floor(startY + h - marginY - 0.5))
+	if x1 < x0 {
+		x0 = int(startX + w/2)
+		x1 = x0
+	}
+	if y1 < y0 {
+		y0 = int(startY + h/2)
+		y1 = y0
+	}
+
+	// Soma O(1) pela imagem integral (intervalo inclusivo -> semiaberto)
+	sum, count := lp.rectSum(x0, y0, x1+1, y1+1)
+	if count == 0 {
+		return 0
+	}
+	return uint8(sum / uint32(count))
+}
+
+// uniformLevelMap: Mapa de limiares constante para a grade
+func (fr *FrameReconstructor) uniformLevelMap(geo gridGeometry, threshold byte, thresholds [3]uint8) *levelMap {
+	return uniformLevelMap(geo.Cols, geo.Rows, threshold, thresholds, fr.FrameCfg.GrayLevels)
+}
+
+// estimateLevelMap: Limiares locais estimados das amostras; tiles ambíguos
+// usam os limiares globais informados
+func (fr *FrameReconstructor) estimateLevelMap(geo gridGeometry, samples []uint8, threshold byte, thresholds [3]uint8) *levelMap {
+	if est, ok := estimateLevels(samples, fr.FrameCfg.GrayLevels); ok {
+		threshold, thresholds = est.Threshold, est.Thresholds
+	}
+	return buildLevelMap(samples, geo.Cols, geo.Rows, fr.FrameCfg.GrayLevels, fr.uniformLevelMap(geo, threshold, thresholds))
+}
+
+// detectedGrid: Detecção de grade com cache da primeira detecção
+// bem-sucedida. Falhas não são memorizadas: o próximo frame que precisar de
+// recuperação tenta de novo.
+func (fr *FrameReconstructor) detectedGrid(lp *lumaPlane, base gridGeometry) (gridGeometry, bool) {
+	vg := &fr.geometry
+	vg.gridMu.Lock()
+	defer vg.gridMu.Unlock()
+	if vg.gridOK {
+		return vg.grid, true
+	}
+	grid, ok := detectGrid(lp, base)
+	if !ok {
+		return base, false
+	}
+	vg.grid, vg.gridOK = grid, true
+	fmt.Printf("🔎 Grade detectada: pitch %.2fx%.2f px, origem (%.2f, %.2f), %dx%d macros\n",
+		grid.PitchX, grid.PitchY, grid.OriginX, grid.OriginY, grid.Cols, grid.Rows)
+	return grid, true
+}
+
+// sampleGrid: Média de luminância de cada macro pixel (ordem raster)
+func (fr *FrameReconstructor) sampleGrid(lp *lumaPlane, geo gridGeometry) []uint8 {
+	// Grade escalada ou imagem reamostrada (scan/captura): bordas borradas
+	margin := 0.0
+	if !geo.exact() || lp.resampled {
+		margin = macroMargin
+	}
+
+	samples := make([]uint8, 0, geo.Cols*geo.Rows)
+	for y := 0; y < geo.Rows; y++ {
+		for x := 0; x < geo.Cols; x++ {
+			// Posição subpixel na imagem
+			targetX := geo.OriginX + float64(x)*geo.PitchX
+			targetY := geo.OriginY + float64(y)*geo.PitchY
+
+			avgY := fr.extractMacroPixel(lp, targetX, targetY, geo.PitchX, geo.PitchY, margin)
+			samples = append(samples, avgY)
+		}
+	}
+	return samples
+}
+
+// samplesToBytes: Classifica as amostras em símbolos e empacota em bytes
+func (fr *FrameReconstructor) samplesToBytes(samples []uint8, lm *levelMap) []byte {
+	bits := make([]byte, len(samples))
+	for i, avgY := range samples {
+		t := lm.at(i)
+		if fr.FrameCfg.GrayLevels == 2 {
+			if avgY >= t[0] {
+				bits[i] = 1
+			}
+		} else {
+			bits[i] = encoder.DynGrayToNibble(avgY, t)
+		}
+	}
+
+	var allBytes []byte
+	if fr.FrameCfg.GrayLevels == 2 {
+		for i := 0; i+7 < len(bits); i += 8 {
+			b := (bits[i] << 7) | (bits[i+1] << 6) | (bits[i+2] << 5) | (bits[i+3] << 4) |
+				(bits[i+4] << 3) | (bits[i+5] << 2) | (bits[i+6] << 1) | bits[i+7]
+			allBytes = append(allBytes, b)
+		}
+	} else {
+		for i := 0; i+3 < len(bits); i += 4 {
+			b := (bits[i] << 6) | (bits[i+1] << 4) | (bits[i+2] << 2) | bits[i+3]
+			allBytes = append(allBytes, b)
+		}
+	}
+	return allBytes
+}
@@ -0,0 +1,272 @@
+package decoder
+
+import (
+	"fmt"
+	"math"
+
+	"ncc/internal/encoder"
+)
+
+// Parâmetros da detecção de marcadores em páginas escaneadas/fotografadas
+const (
+	scanThresholdPct = 15  // Pixel escuro: abaixo da média local - 15%
+	scanWindowDiv    = 24  // Janela da média local = menor lado / scanWindowDiv
+	scanMinModule    = 1.5 // Módulo mínimo (pixels) de um marcador
+	scanMinHits      = 3   // Linhas que confirmam um marcador
+)
+
+// fiducial: Marcador 1:1:3:1:1 encontrado na imagem
+type fiducial struct {
+	point
+	module float64 // Tamanho estimado do módulo (pixels)
+	hits   int     // Linhas que cruzaram o marcador
+}
+
+// scanPlanes: Localiza os quatro marcadores da página, corrige a
+// perspectiva e devolve o frame reamostrado no tamanho do encoder
+func (fr *FrameReconstructor) scanPlanes(lp *lumaPlane) ([]*lumaPlane, error) {
+	corners, err := findPageCorners(lp)
+	if err != nil {
+		return nil, err
+	}
+	var ref [4]point
+	for i, c := range encoder.PageFiducialCenters(fr.FrameCfg) {
+		ref[i] = point{c[0], c[1]}
+	}
+	return fr.orientedPlanes(lp, ref, corners)
+}
+
+// orientedPlanes: Reamostra o quadrilátero corners (TL, TR, BL, BR nos eixos
+// da imagem) para o frame, com ref nas coordenadas do frame. A orientação
+// não é conhecida: retorna as rotações plausíveis (0°/180° ou 90°/270°,
+// pela proporção), e a verificação do header escolhe a correta.
+func (fr *FrameReconstructor) orientedPlanes(lp *lumaPlane, ref, corners [4]point) ([]*lumaPlane, error) {
+	tl, tr, bl, br := corners[0], corners[1], corners[2], corners[3]
+
+	orientations := [][4]point{
+		{tl, tr, bl, br}, // 0°
+		{br, bl, tr, tl}, // 180°
+		{tr, br, tl, bl}, // 90° horário
+		{bl, tl, br, tr}, // 90° anti-horário
+	}
+	// Proporção do quadrilátero oposta à do frame indica imagem deitada
+	width := dist(tl, tr) + dist(bl, br)
+	height := dist(tl, bl) + dist(tr, br)
+	if (width > height) != (fr.FrameCfg.Width > fr.FrameCfg.Height) {
+		orientations = append(orientations[2:], orientations[:2]...)
+	}
+
+	var planes []*lumaPlane
+	for _, img := range orientations[:2] {
+		m, ok := solveHomography(ref, img)
+		if !ok {
+			continue
+		}
+		planes = append(planes, warpPlane(lp, m, fr.FrameCfg.Width, fr.FrameCfg.Height))
+	}
+	if len(planes) == 0 {
+		return nil, fmt.Errorf("cantos degenerados (colineares)")
+	}
+	// Barra de calibração (preto à esquerda, branco à direita) no topo indica
+	// a orientação provável: tenta primeiro, evitando a recuperação na errada
+	if len(planes) == 2 && fr.barContrast(planes[1]) > fr.barContrast(planes[0]) {
+		planes[0], planes[1] = planes[1], planes[0]
+	}
+	return planes, nil
+}
+
+// barContrast: Branco - preto medidos nas seções extremas da barra
+func (fr *FrameReconstructor) barContrast(lp *lumaPlane) int {
+	sectionWidth := lp.W / 4
+	black := fr.measureSectionAverage(lp, 0, 0, sectionWidth, encoder.CalibrationBarHeight)
+	white := fr.measureSectionAverage(lp, 3*sectionWidth, 0, sectionWidth, encoder.CalibrationBarHeight)
+	return int(white) - int(black)
+}
+
+// findPageCorners: Quatro marcadores mais externos (TL, TR, BL, BR nos eixos
+// da imagem). Os marcadores ficam fora da área de dados, então padrões
+// acidentais nos dados nunca são os extremos.
+func findPageCorners(lp *lumaPlane) ([4]point, error) {
+	cands := findFiducials(lp)
+	if len(cands) < 4 {
+		return [4]point{}, fmt.Errorf("marcadores da página não encontrados (%d de 4)", len(cands))
+	}
+
+	fids := cands
+	pick := func(score func(p point) float64) point {
+		best := fids[0].point
+		for _, f := range fids[1:] {
+			if score(f.point) > score(best) {
+				best = f.point
+			}
+		}
+		return best
+	}
+	tl := pick(func(p point) float64 { return -(p.X + p.Y) })
+	tr := pick(func(p point) float64 { return p.X - p.Y })
+	bl := pick(func(p point) float64 { return p.Y - p.X })
+	br := pick(func(p point) float64 { return p.X + p.Y })
+
+	if tl == tr || tl == bl || tl == br || tr == bl || tr == br || bl == br {
+		return [4]point{}, fmt.Errorf("marcadores da página insuficientes")
+	}
+
+	// Os quatro marcadores são iguais: módulos muito diferentes indicam que
+	// um extremo é padrão acidental, não marcador
+	lo, hi := math.Inf(1), 0.0
+	for _, f := range fids {
+		if f.point == tl || f.point == tr || f.point == bl || f.point == br {
+			lo, hi = math.Min(lo, f.module), math.Max(hi, f.module)
+		}
+	}
+	if hi > lo*2 {
+		return [4]point{}, fmt.Errorf("marcadores da página inconsistentes (módulo %.1f-%.1f px)", lo, hi)
+	}
+	return [4]point{tl, tr, bl, br}, nil
+}
+
+// findFiducials: Varre linhas procurando a sequência escuro/claro/escuro/
+// claro/escuro em 1:1:3:1:1, confirma na coluna e agrupa os acertos
+func findFiducials(lp *lumaPlane) []fiducial {
+	dark := binarizeAdaptive(lp)
+	w, h := lp.W, lp.H
+
+	var clusters []fiducial
+	runs := make([]int, 0, 64)
+	for y := 0; y < h; y++ {
+		row := dark[y*w : (y+1)*w]
+
+		// Comprimentos de corrida alternados, começando pelo primeiro pixel
+		runs = runs[:0]
+		start := 0
+		for x := 1; x <= w; x++ {
+			if x == w || row[x] != row[start] {
+				runs = append(runs, x-start)
+				start = x
+			}
+		}
+		firstDark := row[0]
+
+		pos := 0
+		for i := 0; i+5 <= len(runs); i++ {
+			isDark := (i%2 == 0) == firstDark
+			if isDark {
+				r := runs[i : i+5]
+				if module, ok := finderRatio(r); ok {
+					cx := float64(pos+r[0]+r[1]) + float64(r[2])/2
+					if cy, vmod, ok := crossCheckVertical(dark, w, h, int(cx), y, module); ok {
+						addFiducial(&clusters, point{cx, cy}, (module+vmod)/2)
+					}
+				}
+			}
+			pos += runs[i]
+		}
+	}
+
+	var out []fiducial
+	for _, c := range clusters {
+		if c.hits >= scanMinHits {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// finderRatio: Confere 1:1:3:1:1 e estima o módulo
+func finderRatio(r []int) (float64, bool) {
+	total := r[0] + r[1] + r[2] + r[3] + r[4]
+	module := float64(total) / 7
+	if module < scanMinModule {
+		return 0, false
+	}
+	tol := module * 0.6
+	return module, math.Abs(float64(r[0])-module) < tol &&
+		math.Abs(float64(r[1])-module) < tol &&
+		math.Abs(float64(r[2])-3*module) < 3*tol &&
+		math.Abs(float64(r[3])-module) < tol &&
+		math.Abs(float64(r[4])-module) < tol
+}
+
+// crossCheckVertical: Mede as corridas na coluna x a partir de (x, y) e
+// retorna o centro vertical refinado
+func crossCheckVertical(dark []bool, w, h, x, y int, module float64) (float64, float64, bool) {
+	at := func(yy int) bool { return dark[yy*w+x] }
+	if !at(y) {
+		return 0, 0, false
+	}
+	limit := int(module * 5)
+
+	var r [5]int
+	// Centro (para cima e para baixo)
+	up := y
+	for up >= 0 && at(up) && y-up <= limit {
+		up--
+	}
+	down := y
+	for down < h && at(down) && down-y <= limit {
+		down++
+	}
+	r[2] = down - up - 1
+
+	// Anel claro e anel escuro acima
+	yy := up
+	for yy >= 0 && !at(yy) && r[1] <= limit {
+		r[1]++
+		yy--
+	}
+	for yy >= 0 && at(yy) && r[0] <= limit {
+		r[0]++
+		yy--
+	}
+	// Abaixo
+	yy = down
+	for yy < h && !at(yy) && r[3] <= limit {
+		r[3]++
+		yy++
+	}
+	for yy < h && at(yy) && r[4] <= limit {
+		r[4]++
+		yy++
+	}
+
+	vmod, ok := finderRatio(r[:])
+	if !ok || vmod > module*1.5 || vmod < module/1.5 {
+		return 0, 0, false
+	}
+	return float64(up+1) + float64(r[2])/2, vmod, true
+}
+
+// addFiducial: Agrupa acertos do mesmo marcador (média dos centros)
+func addFiducial(clusters *[]fiducial, p point, module float64) {
+	for i := range *clusters {
+		c := &(*clusters)[i]
+		if math.Abs(c.X-p.X) < c.module*3 && math.Abs(c.Y-p.Y) < c.module*3 {
+			n := float64(c.hits)
+			c.X = (c.X*n + p.X) / (n + 1)
+			c.Y = (c.Y*n + p.Y) / (n + 1)
+			c.module = (c.module*n + module) / (n + 1)
+			c.hits++
+			return
+		}
+	}
+	*clusters = append(*clusters, fiducial{point: p, module: module, hits: 1})
+}
+
+// binarizeAdaptive: Escuro = abaixo da média local (imagem integral), o que
+// tolera sombras e iluminação irregular de fotos
+func binarizeAdaptive(lp *lumaPlane) []bool {
+	w, h := lp.W, lp.H
+	r := max(4, min(w, h)/scanWindowDiv/2)
+	dark := make([]bool, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			sum, count := lp.rectSum(x-r, y-r, x+r+1, y+r+1)
+			dark[y*w+x] = uint64(lp.Pix[y*w+x])*uint64(count)*100 < uint64(sum)*(100-scanThresholdPct)
+		}
+	}
+	return dark
+}
+
+func dist(a, b point) float64 {
+	return math.Hypot(a.X-b.X, a.Y-b.Y)
+}
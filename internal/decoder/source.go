@@ -0,0 +1,368 @@
+package decoder
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"ncc/internal/encoder"
+)
+
+// FrameSource: Origem sequencial de frames para o reconstrutor. Next
+// retorna io.EOF ao final; a decodificação da imagem é adiada para os
+// workers (FrameRef.load).
+type FrameSource interface {
+	Next() (FrameRef, error)
+	Close() error
+}
+
+// FrameRef: Frame da origem com carga preguiçosa
+type FrameRef struct {
+	Name string // Caminho ou "frame N" (diagnóstico)
+	load func() (*lumaPlane, error)
+}
+
+// OpenSource: Escolhe a origem pela entrada. Diretório ou glob de imagens
+// (PNG/JPEG), imagem avulsa, .y4m e .nccv são lidos em Go puro; demais
+// formatos passam pelo FFmpeg.
+func OpenSource(inputPath string) (FrameSource, error) {
+	if info, err := os.Stat(inputPath); err == nil && info.IsDir() {
+		return NewImageDirSource(inputPath)
+	} else if err != nil && strings.ContainsAny(inputPath, "*?[") {
+		return NewImageGlobSource(inputPath)
+	}
+	if isImageFile(inputPath) {
+		return NewPathSource([]string{inputPath}), nil
+	}
+
+	switch strings.ToLower(filepath.Ext(inputPath)) {
+	case ".y4m":
+		f, err := os.Open(inputPath)
+		if err != nil {
+			return nil, err
+		}
+		src, err := newY4MSource(f, f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return src, nil
+	case ".nccv":
+		return newNCCVSource(inputPath)
+	}
+	return newFFmpegSource(inputPath)
+}
+
+// pathSource: Lista de imagens em disco (PNG/JPEG)
+type pathSource struct {
+	paths []string
+	next  int
+}
+
+// NewPathSource: Origem a partir de caminhos de imagem, na ordem dada
+func NewPathSource(paths []string) FrameSource {
+	return &pathSource{paths: paths}
+}
+
+// NewImageDirSource: Todas as imagens do diretório. A ordem dos nomes é
+// irrelevante: a montagem usa o FrameIndex do header.
+func NewImageDirSource(dir string) (FrameSource, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, entry := range entries {
+		if !entry.IsDir() && isImageFile(entry.Name()) {
+			paths = append(paths, filepath.Join(dir, entry.Name()))
+		}
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("nenhuma imagem (.png/.jpg) em %s", dir)
+	}
+	sort.Strings(paths)
+	return NewPathSource(paths), nil
+}
+
+// NewImageGlobSource: Imagens que casam com o padrão (ex.: "shots/*.jpg")
+func NewImageGlobSource(pattern string) (FrameSource, error) {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("padrão inválido %q: %w", pattern, err)
+	}
+	var paths []string
+	for _, m := range matches {
+		if info, err := os.Stat(m); err == nil && !info.IsDir() && isImageFile(m) {
+			paths = append(paths, m)
+		}
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("nenhuma imagem (.png/.jpg) casa com %s", pattern)
+	}
+	sort.Strings(paths)
+	return NewPathSource(paths), nil
+}
+
+// isImageFile: Extensões aceitas como frame avulso
+func isImageFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".png", ".jpg", ".jpeg":
+		return true
+	}
+	return false
+}
+
+func (s *pathSource) Next() (FrameRef, error) {
+	if s.next >= len(s.paths) {
+		return FrameRef{}, io.EOF
+	}
+	path := s.paths[s.next]
+	s.next++
+	return FrameRef{Name: path, load: func() (*lumaPlane, error) {
+		return loadImageFile(path)
+	}}, nil
+}
+
+func (s *pathSource) Len() int {
+	return len(s.paths)
+}
+
+func (s *pathSource) Close() error {
+	return nil
+}
+
+// loadImageFile: Decodifica a imagem e converte para plano Y
+func loadImageFile(path string) (*lumaPlane, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("decode image %s: %w", filepath.Base(path), err)
+	}
+	return newLumaPlane(img), nil
+}
+
+// y4mSource: YUV4MPEG2 (arquivo ou pipe do FFmpeg). Apenas o plano Y é
+// mantido; croma é descartado.
+type y4mSource struct {
+	r          *bufio.Reader
+	closer     io.Closer
+	w, h       int
+	chromaSize int
+	count      int
+}
+
+func newY4MSource(r io.Reader, closer io.Closer) (*y4mSource, error) {
+	s := &y4mSource{r: bufio.NewReaderSize(r, 4*1024*1024), closer: closer}
+
+	line, err := s.r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("read y4m header: %w", err)
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 || fields[0] != "YUV4MPEG2" {
+		return nil, fmt.Errorf("invalid y4m signature")
+	}
+
+	colorspace := "420jpeg" // Padrão da especificação
+	for _, f := range fields[1:] {
+		switch f[0] {
+		case 'W':
+			s.w, _ = strconv.Atoi(f[1:])
+		case 'H':
+			s.h, _ = strconv.Atoi(f[1:])
+		case 'C':
+			colorspace = f[1:]
+		}
+	}
+	if s.w <= 0 || s.h <= 0 {
+		return nil, fmt.Errorf("invalid y4m size %dx%d", s.w, s.h)
+	}
+
+	cw, ch := (s.w+1)/2, (s.h+1)/2
+	switch {
+	case strings.HasPrefix(colorspace, "420"):
+		s.chromaSize = 2 * cw * ch
+	case strings.HasPrefix(colorspace, "422"):
+		s.chromaSize = 2 * cw * s.h
+	case strings.HasPrefix(colorspace, "444"):
+		s.chromaSize = 2 * s.w * s.h
+	case colorspace == "mono":
+		s.chromaSize = 0
+	default:
+		return nil, fmt.Errorf("unsupported y4m colorspace C%s", colorspace)
+	}
+	return s, nil
+}
+
+func (s *y4mSource) Next() (FrameRef, error) {
+	line, err := s.r.ReadString('\n')
+	if err == io.EOF && line == "" {
+		return FrameRef{}, io.EOF
+	}
+	if err != nil {
+		return FrameRef{}, fmt.Errorf("read y4m frame header: %w", err)
+	}
+	if !strings.HasPrefix(line, "FRAME") {
+		return FrameRef{}, fmt.Errorf("invalid y4m frame marker %q", strings.TrimSpace(line))
+	}
+
+	pix := make([]byte, s.w*s.h)
+	if _, err := io.ReadFull(s.r, pix); err != nil {
+		return FrameRef{}, fmt.Errorf("read y4m frame %d: %w", s.count, err)
+	}
+	if _, err := s.r.Discard(s.chromaSize); err != nil {
+		return FrameRef{}, fmt.Errorf("read y4m frame %d: %w", s.count, err)
+	}
+	s.count++
+
+	w, h := s.w, s.h
+	return FrameRef{Name: fmt.Sprintf("frame %d", s.count), load: func() (*lumaPlane, error) {
+		return newLumaPlaneFromPix(w, h, pix), nil
+	}}, nil
+}
+
+func (s *y4mSource) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer.Close()
+}
+
+// nccvSource: Container .nccv do encoder
+type nccvSource struct {
+	f      *os.File
+	r      *bufio.Reader
+	w, h   int
+	frames int
+	next   int
+}
+
+func newNCCVSource(path string) (*nccvSource, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	var header [encoder.NCCVHeaderSize]byte
+	if _, err := io.ReadFull(f, header[:]); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("read nccv header: %w", err)
+	}
+	if !bytes.Equal(header[0:4], encoder.NCCVMagic[:]) {
+		f.Close()
+		return nil, fmt.Errorf("invalid nccv signature")
+	}
+	if header[4] != 1 || header[5] != 0 {
+		f.Close()
+		return nil, fmt.Errorf("unsupported nccv version %d / pixel format %d", header[4], header[5])
+	}
+
+	s := &nccvSource{
+		f:      f,
+		r:      bufio.NewReaderSize(f, 4*1024*1024),
+		w:      int(binary.BigEndian.Uint32(header[8:12])),
+		h:      int(binary.BigEndian.Uint32(header[12:16])),
+		frames: int(binary.BigEndian.Uint32(header[20:24])),
+	}
+	if s.w <= 0 || s.h <= 0 {
+		f.Close()
+		return nil, fmt.Errorf("invalid nccv size %dx%d", s.w, s.h)
+	}
+	return s, nil
+}
+
+func (s *nccvSource) Next() (FrameRef, error) {
+	if s.next >= s.frames {
+		return FrameRef{}, io.EOF
+	}
+	pix := make([]byte, s.w*s.h)
+	if _, err := io.ReadFull(s.r, pix); err != nil {
+		return FrameRef{}, fmt.Errorf("read nccv frame %d: %w", s.next, err)
+	}
+	s.next++
+
+	w, h := s.w, s.h
+	return FrameRef{Name: fmt.Sprintf("frame %d", s.next), load: func() (*lumaPlane, error) {
+		return newLumaPlaneFromPix(w, h, pix), nil
+	}}, nil
+}
+
+func (s *nccvSource) Len() int {
+	return s.frames
+}
+
+func (s *nccvSource) Close() error {
+	return s.f.Close()
+}
+
+// ffmpegSource: Decodifica o vídeo pelo FFmpeg direto em Y4M mono (pipe),
+// sem PNGs temporários
+type ffmpegSource struct {
+	*y4mSource
+	cmd *exec.Cmd
+}
+
+func newFFmpegSource(videoPath string) (*ffmpegSource, error) {
+	if _, err := os.Stat(videoPath); err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(findFFmpeg(),
+		"-hwaccel", "auto",
+		"-i", videoPath,
+		"-vsync", "0",
+		"-pix_fmt", "gray", // Apenas Y
+		"-f", "yuv4mpegpipe",
+		"-strict", "-1", // Cmono não é padrão em Y4M
+		"pipe:1",
+	)
+	cmd.Stderr = os.Stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("stdout pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start ffmpeg: %w", err)
+	}
+
+	y4m, err := newY4MSource(stdout, nil)
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, fmt.Errorf("falha na extração ffmpeg: %w", err)
+	}
+	return &ffmpegSource{y4mSource: y4m, cmd: cmd}, nil
+}
+
+func (s *ffmpegSource) Next() (FrameRef, error) {
+	ref, err := s.y4mSource.Next()
+	if err == io.EOF && s.cmd.ProcessState == nil {
+		// Erro do FFmpeg no meio do vídeo aparece aqui como EOF
+		if werr := s.cmd.Wait(); werr != nil {
+			return FrameRef{}, fmt.Errorf("falha na extração ffmpeg: %w", werr)
+		}
+	}
+	return ref, err
+}
+
+func (s *ffmpegSource) Close() error {
+	if s.cmd.ProcessState == nil {
+		s.cmd.Process.Kill()
+		s.cmd.Wait()
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package decoder
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"ncc/internal/encoder"
+)
+
+// TestSinkSourceRoundTrip: Encode para cada destino Go puro e decode pela
+// origem correspondente (OpenSource escolhe pela saída)
+func TestSinkSourceRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	data := make([]byte, 40000) // Alguns frames no preset padrão
+	rand.New(rand.NewSource(1)).Read(data)
+	input := filepath.Join(dir, "payload.bin")
+	if err := os.WriteFile(input, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, output := range []string{"frames" + string(os.PathSeparator), "video.y4m", "video.nccv"} {
+		path := filepath.Join(dir, output)
+		kind := encoder.SinkKind(path)
+		enc, err := encoder.NewVideoEncoder("low", 2, "default", "none")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = enc.EncodeFile(input, path, nil)
+		enc.Cleanup()
+		if err != nil {
+			t.Fatalf("%s: encode: %v", kind, err)
+		}
+
+		src, err := OpenSource(path)
+		if err != nil {
+			t.Fatalf("%s: %v", kind, err)
+		}
+		got := filepath.Join(dir, kind+".out")
+		err = NewFrameReconstructor("default").ReconstructSource(src, got, nil)
+		src.Close()
+		if err != nil {
+			t.Fatalf("%s: decode: %v", kind, err)
+		}
+		out, err := os.ReadFile(got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(out, data) {
+			t.Errorf("%s: %d bytes lidos, difere do original (%d bytes)", kind, len(out), len(data))
+		}
+	}
+}
+
+// TestReconstructOutOfOrderGap: Frames em ordem inversa e um frame do meio
+// ausente (-partial): o payload é escrito por índice, com a lacuna zerada
+func TestReconstructOutOfOrderGap(t *testing.T) {
+	dir := t.TempDir()
+	data := make([]byte, 60000)
+	rand.New(rand.NewSource(3)).Read(data)
+	input := filepath.Join(dir, "payload.bin")
+	if err := os.WriteFile(input, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	framesDir := filepath.Join(dir, "frames") + string(os.PathSeparator)
+	enc, err := encoder.NewVideoEncoder("low", 2, "default", "none")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = enc.EncodeFile(input, framesDir, nil)
+	enc.Cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths, err := filepath.Glob(filepath.Join(framesDir, "*.png"))
+	if err != nil || len(paths) < 4 {
+		t.Fatalf("%d frames, err = %v", len(paths), err)
+	}
+	sort.Strings(paths)
+	drop := 2
+	var reversed []string
+	for i := len(paths) - 1; i >= 0; i-- {
+		if i != drop {
+			reversed = append(reversed, paths[i])
+		}
+	}
+
+	out := filepath.Join(dir, "out.bin")
+	if err := NewFrameReconstructor("default").ReconstructSource(NewPathSource(reversed), out, nil); err == nil {
+		t.Fatal("frame ausente aceito sem -partial")
+	}
+	if _, err := os.Stat(out); err == nil {
+		t.Error("saída incompleta ficou no disco")
+	}
+
+	fr := NewFrameReconstructor("default")
+	fr.Partial = true
+	if err := fr.ReconstructSource(NewPathSource(reversed), out, nil); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fr.Gaps) != 1 || len(got) != len(data) {
+		t.Fatalf("lacunas %v, %d bytes (want 1 lacuna, %d bytes)", fr.Gaps, len(got), len(data))
+	}
+	gap := fr.Gaps[0]
+	want := append([]byte(nil), data...)
+	clear(want[gap.Start:gap.End])
+	if !bytes.Equal(got, want) {
+		t.Error("payload difere fora da lacuna")
+	}
+}
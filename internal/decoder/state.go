@@ -0,0 +1,90 @@
+package decoder
+
+import (
+	"image"
+	"sync"
+
+	"ncc/internal/encoder"
+)
+
+// geometryLockVotes: Frames verificados (CRC OK) com a mesma geometria
+// necessários para travá-la no nível do vídeo
+const geometryLockVotes = 3
+
+// frameState: Estado de leitura de um único frame. Nunca é compartilhado
+// entre workers; sobrescritas da recuperação valem apenas para o frame.
+type frameState struct {
+	geo       gridGeometry
+	threshold byte     // Limiar binário (barra de calibração)
+	levels    [3]uint8 // Limiares 4-níveis (barra de calibração)
+}
+
+// frameDecode: Resultado da leitura de um frame com uma dada geometria
+type frameDecode struct {
+	data   []byte
+	header encoder.FrameHeader
+	crcOK  bool
+	err    error
+	geo    gridGeometry
+}
+
+// verified: Header válido e CRC dos dados conferido
+func (d frameDecode) verified() bool {
+	return d.err == nil && d.crcOK
+}
+
+// geometryKey: Geometria votada para uma resolução de imagem
+type geometryKey struct {
+	bounds image.Rectangle
+	geo    gridGeometry
+}
+
+// videoGeometry: Geometria compartilhada do vídeo. É estabelecida pelos
+// primeiros frames decodificados com confiança e, a partir daí, apenas lida
+// pelos workers.
+type videoGeometry struct {
+	mu     sync.RWMutex
+	locked bool
+	bounds image.Rectangle
+	geo    gridGeometry
+	votes  map[geometryKey]int
+
+	// Grade detectada na imagem (só uma detecção bem-sucedida fica em cache;
+	// frames em branco ou danificados não desativam a detecção)
+	gridMu sync.Mutex
+	grid   gridGeometry
+	gridOK bool
+}
+
+// lockedFor: Geometria travada, se compatível com a resolução da imagem
+func (vg *videoGeometry) lockedFor(bounds image.Rectangle) (gridGeometry, bool) {
+	vg.mu.RLock()
+	defer vg.mu.RUnlock()
+	if !vg.locked || vg.bounds != bounds {
+		return gridGeometry{}, false
+	}
+	return vg.geo, true
+}
+
+// confirm: Registra um frame verificado; trava a geometria ao atingir
+// geometryLockVotes
+func (vg *videoGeometry) confirm(bounds image.Rectangle, geo gridGeometry) bool {
+	vg.mu.Lock()
+	defer vg.mu.Unlock()
+	if vg.locked {
+		return false
+	}
+	if vg.votes == nil {
+		vg.votes = make(map[geometryKey]int)
+	}
+	key := geometryKey{bounds: bounds, geo: geo}
+	vg.votes[key]++
+	if vg.votes[key] < geometryLockVotes {
+		return false
+	}
+	vg.locked = true
+	vg.bounds = bounds
+	vg.geo = geo
+	vg.votes = nil
+	return true
+}
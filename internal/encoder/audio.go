@@ -0,0 +1,74 @@
+package encoder
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+
+	"ncc/internal/audio"
+)
+
+// Modos da faixa de áudio (-audio)
+const (
+	AudioNone     = "none"
+	AudioManifest = "manifest" // Cópia redundante do frame 0 (headers + dados)
+	AudioPayload  = "payload"  // Cópia completa do payload (arquivos pequenos)
+)
+
+// ValidAudioMode: Modo aceito por -audio ("" equivale a none)
+func ValidAudioMode(mode string) bool {
+	switch mode {
+	case "", AudioNone, AudioManifest, AudioPayload:
+		return true
+	}
+	return false
+}
+
+// prepareAudio: Renderiza a faixa de áudio cobrindo a duração do vídeo.
+// Saídas FFmpeg recebem a faixa no mux (WAV temporário); Y4M, NCCV e
+// sequências PNG ganham um .wav ao lado.
+func (ve *VideoEncoder) prepareAudio(outputPath string, frame0 *Frame, data []byte, totalFrames int) error {
+	if ve.AudioMode == "" || ve.AudioMode == AudioNone {
+		return nil
+	}
+	kind := SinkKind(outputPath)
+	if ve.Pages || kind == "pdf" {
+		fmt.Println("⚠️  Faixa de áudio ignorada em páginas impressas")
+		return nil
+	}
+
+	pkt := audio.Packet{Kind: audio.KindPayload, Data: data}
+	if ve.AudioMode == AudioManifest {
+		header, err := frame0.Header.Encode()
+		if err != nil {
+			return err
+		}
+		pkt = audio.Packet{Kind: audio.KindManifest, Data: append(header, frame0.Data...)}
+	}
+
+	videoSeconds := float64(totalFrames) / float64(ve.FrameCfg.FPS)
+	samples, copies, err := audio.Render(pkt, videoSeconds)
+	if err != nil {
+		return fmt.Errorf("render audio: %w", err)
+	}
+
+	path := audio.SidecarPath(outputPath)
+	if kind == "ffmpeg" {
+		path = filepath.Join(ve.TempDir, "audio.wav")
+		ve.audioTrack = path
+	}
+	if err := audio.WriteWAVFile(path, samples, audio.SampleRate); err != nil {
+		return err
+	}
+
+	cycle := audio.Duration(len(pkt.Data))
+	fmt.Printf("🔊 Faixa de áudio (%s): %d bytes, %.1fs por cópia, %d cópias\n",
+		ve.AudioMode, len(pkt.Data), cycle, copies)
+	if audioSeconds := float64(len(samples)) / audio.SampleRate; audioSeconds > videoSeconds+1 {
+		fmt.Printf("ℹ️  Áudio (%.0fs) mais longo que o vídeo (%.0fs)\n", math.Ceil(audioSeconds), math.Ceil(videoSeconds))
+	}
+	if kind != "ffmpeg" {
+		fmt.Printf("🔊 Faixa de áudio em %s (mantenha ao lado do vídeo)\n", path)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package encoder
+
+import "math"
+
+// ModulationDCT: Cada bloco MacroSize x MacroSize carrega um bit por
+// coeficiente DCT de baixa frequência (sinal do coeficiente). Codecs com
+// perdas quantizam pouco essas frequências, e o sinal não depende de
+// ganho/offset de luminância (sem calibração de níveis).
+const ModulationDCT = "dct"
+
+// DCTCoefficients: Coeficientes (u horizontal, v vertical) modulados, na
+// ordem dos bits do bloco (MSB primeiro). DC fica fixo em cinza médio.
+var DCTCoefficients = [][2]int{
+	{1, 0}, {0, 1}, {1, 1}, {2, 0},
+	{0, 2}, {2, 1}, {1, 2}, {2, 2},
+}
+
+// Amplitude de cada coeficiente (pico do padrão, níveis de cinza) e DC
+const (
+	DCTAmplitude = 24
+	DCTDC        = 128
+)
+
+// DCTFrameConfig: 720p com blocos 16x16 de 8 bits (8x o binário padrão)
+func DCTFrameConfig() FrameConfig {
+	return FrameConfig{
+		Width:             1280,
+		Height:            720,
+		MacroSize:         16,
+		FPS:               30,
+		CalibrationHeight: 16,
+		GrayLevels:        2, // Não usado na modulação DCT
+		Modulation:        ModulationDCT,
+	}
+}
+
+// DCTPattern: Base DCT contínua no ponto (fx, fy) do bloco, em frações do
+// lado (centro do pixel x = (x+0.5)/lado). O decoder amostra a mesma
+// função em qualquer escala.
+func DCTPattern(u, v int, fx, fy float64) float64 {
+	return math.Cos(math.Pi*float64(u)*fx) * math.Cos(math.Pi*float64(v)*fy)
+}
+
+// dctBlocks: Pixels de cada símbolo (2^bits blocos de size*size)
+func dctBlocks(size int) [][]uint8 {
+	bits := len(DCTCoefficients)
+	blocks := make([][]uint8, 1<<bits)
+	for sym := range blocks {
+		block := make([]uint8, size*size)
+		for y := 0; y < size; y++ {
+			fy := (float64(y) + 0.5) / float64(size)
+			for x := 0; x < size; x++ {
+				fx := (float64(x) + 0.5) / float64(size)
+				v := float64(DCTDC)
+				for k, c := range DCTCoefficients {
+					sign := -1.0
+					if sym>>(bits-1-k)&1 == 1 {
+						sign = 1
+					}
+					v += sign * DCTAmplitude * DCTPattern(c[0], c[1], fx, fy)
+				}
+				block[y*size+x] = uint8(math.Max(0, math.Min(255, math.Round(v))))
+			}
+		}
+		blocks[sym] = block
+	}
+	return blocks
+}
@@ -0,0 +1,27 @@
+package encoder
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGlobalHeaderLayout(t *testing.T) {
+	gh := GlobalHeader{IndexRoot: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}, TotalFrames: 0x0a0b0c0d}
+	gh.SetArchive([6]byte{0x11, 0x12, 0x13, 0x14, 0x15, 0x16}, 0x0102)
+	raw := gh.Encode()
+	want := []byte{
+		1, 2, 3, 4, 5, 6, 7, 8, // IndexRoot
+		0x0a, 0x0b, 0x0c, 0x0d, // TotalFrames
+		0x01, 0x02, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, // Volume | ID
+	}
+	if !bytes.Equal(raw, want) {
+		t.Fatalf("Encode = %x, want %x", raw, want)
+	}
+	got, err := DecodeGlobalHeader(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != gh {
+		t.Fatalf("DecodeGlobalHeader = %+v, want %+v", got, gh)
+	}
+}
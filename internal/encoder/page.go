@@ -0,0 +1,194 @@
+package encoder
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+)
+
+// Layout da página impressa: A4 a 200 DPI, frame centralizado em escala 1:1
+// e quatro marcadores (fiducials) no estilo QR fora da área de dados
+const (
+	PageDPI         = 200
+	PageWidth       = 1654 // 210 mm
+	PageHeight      = 2339 // 297 mm
+	FiducialModule  = 8    // Pixels por módulo do marcador (1 mm)
+	FiducialModules = 7    // Marcador 7x7 módulos (1:1:3:1:1)
+	FiducialGap     = 56   // Centro do marcador até a borda do frame
+	pageTextScale   = 4    // Pixels por ponto da fonte 5x7
+	pageTextGap     = 72   // Marcadores inferiores até o número da página
+	pageBackground  = 255
+	pageInk         = 0
+)
+
+// PaperFrameConfig: Frame para impressão/scan (macro pixels de 2 mm, binário)
+func PaperFrameConfig() FrameConfig {
+	return FrameConfig{
+		Width:             1200,
+		Height:            1600,
+		MacroSize:         16,
+		FPS:               1,
+		CalibrationHeight: 16,
+		GrayLevels:        2,
+	}
+}
+
+// PageFiducialCenters: Centros dos marcadores em coordenadas do frame
+// (TL, TR, BL, BR). O decoder usa os mesmos pontos para a homografia.
+func PageFiducialCenters(cfg FrameConfig) [4][2]float64 {
+	g := float64(FiducialGap)
+	w, h := float64(cfg.Width), float64(cfg.Height)
+	return [4][2]float64{{-g, -g}, {w + g, -g}, {-g, h + g}, {w + g, h + g}}
+}
+
+// pageOrigin: Canto superior esquerdo do frame na página
+func pageOrigin(cfg FrameConfig) (int, int) {
+	return (PageWidth - cfg.Width) / 2, (PageHeight - cfg.Height) / 2
+}
+
+// RenderPage: Compõe frame + marcadores + número da página (cinza)
+func RenderPage(dst *image.Gray, framePix []byte, cfg FrameConfig, page, total int) error {
+	if dst.Rect.Dx() != PageWidth || dst.Rect.Dy() != PageHeight {
+		return fmt.Errorf("page buffer must be %dx%d", PageWidth, PageHeight)
+	}
+	if cfg.Width+4*FiducialGap > PageWidth || cfg.Height+4*FiducialGap+pageTextGap > PageHeight {
+		return fmt.Errorf("frame %dx%d does not fit on the page", cfg.Width, cfg.Height)
+	}
+	for i := range dst.Pix {
+		dst.Pix[i] = pageBackground
+	}
+
+	ox, oy := pageOrigin(cfg)
+	for y := 0; y < cfg.Height; y++ {
+		copy(dst.Pix[(oy+y)*dst.Stride+ox:], framePix[y*cfg.Width:(y+1)*cfg.Width])
+	}
+
+	for _, c := range PageFiducialCenters(cfg) {
+		drawFiducial(dst, ox+int(c[0]), oy+int(c[1]))
+	}
+
+	label := fmt.Sprintf("%d/%d", page, total)
+	textW := len(label)*6*pageTextScale - pageTextScale
+	drawText(dst, (PageWidth-textW)/2, oy+cfg.Height+FiducialGap+pageTextGap, label)
+	return nil
+}
+
+// drawFiducial: Marcador 1:1:3:1:1 (anel escuro, anel claro, centro 3x3)
+func drawFiducial(dst *image.Gray, cx, cy int) {
+	half := FiducialModules * FiducialModule / 2
+	for my := 0; my < FiducialModules; my++ {
+		for mx := 0; mx < FiducialModules; mx++ {
+			ring := min(mx, my, FiducialModules-1-mx, FiducialModules-1-my)
+			var v uint8 = pageBackground
+			if ring != 1 {
+				v = pageInk
+			}
+			fillRect(dst, cx-half+mx*FiducialModule, cy-half+my*FiducialModule, FiducialModule, FiducialModule, v)
+		}
+	}
+}
+
+func fillRect(dst *image.Gray, x0, y0, w, h int, v uint8) {
+	for y := y0; y < y0+h; y++ {
+		row := dst.Pix[y*dst.Stride:]
+		for x := x0; x < x0+w; x++ {
+			row[x] = v
+		}
+	}
+}
+
+// pageFont: Fonte bitmap 5x7 (bit 4 = coluna esquerda)
+var pageFont = map[rune][7]uint8{
+	'0': {0x0E, 0x11, 0x13, 0x15, 0x19, 0x11, 0x0E},
+	'1': {0x04, 0x0C, 0x04, 0x04, 0x04, 0x04, 0x0E},
+	'2': {0x0E, 0x11, 0x01, 0x02, 0x04, 0x08, 0x1F},
+	'3': {0x1F, 0x02, 0x04, 0x02, 0x01, 0x11, 0x0E},
+	'4': {0x02, 0x06, 0x0A, 0x12, 0x1F, 0x02, 0x02},
+	'5': {0x1F, 0x10, 0x1E, 0x01, 0x01, 0x11, 0x0E},
+	'6': {0x06, 0x08, 0x10, 0x1E, 0x11, 0x11, 0x0E},
+	'7': {0x1F, 0x01, 0x02, 0x04, 0x08, 0x08, 0x08},
+	'8': {0x0E, 0x11, 0x11, 0x0E, 0x11, 0x11, 0x0E},
+	'9': {0x0E, 0x11, 0x11, 0x0F, 0x01, 0x02, 0x0C},
+	'/': {0x01, 0x01, 0x02, 0x04, 0x08, 0x10, 0x10},
+}
+
+// drawText: Texto curto (dígitos e '/') com a fonte 5x7
+func drawText(dst *image.Gray, x, y int, s string) {
+	for _, r := range s {
+		glyph := pageFont[r]
+		for gy, bits := range glyph {
+			for gx := 0; gx < 5; gx++ {
+				if bits&(0x10>>gx) != 0 {
+					fillRect(dst, x+gx*pageTextScale, y+gy*pageTextScale, pageTextScale, pageTextScale, pageInk)
+				}
+			}
+		}
+		x += 6 * pageTextScale
+	}
+}
+
+// pageSink: Páginas imprimíveis. PDF (uma imagem por página A4) ou
+// diretório com page_001.png, page_002.png...
+type pageSink struct {
+	cfg   FrameConfig
+	total int
+	page  *image.Gray
+	count int
+
+	dir string
+	pdf *pdfWriter
+}
+
+func newPageSink(outputPath string, cfg FrameConfig, totalFrames int) (*pageSink, error) {
+	s := &pageSink{
+		cfg:   cfg,
+		total: totalFrames,
+		page:  image.NewGray(image.Rect(0, 0, PageWidth, PageHeight)),
+	}
+	if SinkKind(outputPath) == "pdf" {
+		pdf, err := newPDFWriter(outputPath)
+		if err != nil {
+			return nil, err
+		}
+		s.pdf = pdf
+		return s, nil
+	}
+	if SinkKind(outputPath) != "png" {
+		return nil, fmt.Errorf("saída de impressão deve ser .pdf ou diretório: %s", outputPath)
+	}
+	if err := os.MkdirAll(outputPath, 0755); err != nil {
+		return nil, fmt.Errorf("create page dir: %w", err)
+	}
+	s.dir = outputPath
+	return s, nil
+}
+
+func (s *pageSink) WriteFrame(pix []byte) error {
+	s.count++
+	if err := RenderPage(s.page, pix, s.cfg, s.count, s.total); err != nil {
+		return err
+	}
+	if s.pdf != nil {
+		return s.pdf.AddGrayPage(s.page.Pix, PageWidth, PageHeight, PageDPI)
+	}
+
+	path := filepath.Join(s.dir, fmt.Sprintf("page_%03d.png", s.count))
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", path, err)
+	}
+	if err := png.Encode(f, s.page); err != nil {
+		f.Close()
+		return fmt.Errorf("encode png: %w", err)
+	}
+	return f.Close()
+}
+
+func (s *pageSink) Close() error {
+	if s.pdf != nil {
+		return s.pdf.Close()
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package encoder
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"os"
+)
+
+// pdfWriter: PDF mínimo escrito à mão, uma imagem cinza (FlateDecode) por
+// página. Objetos são gravados em streaming; catálogo, árvore de páginas e
+// xref vão no Close.
+//
+// Numeração: 1 = Catálogo, 2 = Pages, depois (imagem, conteúdo, página)
+// para cada página.
+type pdfWriter struct {
+	f       *os.File
+	w       *bufio.Writer
+	offset  int
+	offsets map[int]int // Objeto -> offset no arquivo
+	pages   []int       // Objetos de página
+	nextObj int
+	closed  bool
+}
+
+func newPDFWriter(path string) (*pdfWriter, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("create pdf: %w", err)
+	}
+	p := &pdfWriter{
+		f:       f,
+		w:       bufio.NewWriterSize(f, 1024*1024),
+		offsets: make(map[int]int),
+		nextObj: 3,
+	}
+	// Comentário binário: sinaliza conteúdo 8-bit para transferências
+	p.write([]byte("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n"))
+	return p, nil
+}
+
+func (p *pdfWriter) write(b []byte) {
+	n, _ := p.w.Write(b) // Erro é retornado no Flush
+	p.offset += n
+}
+
+func (p *pdfWriter) beginObj(num int) {
+	p.offsets[num] = p.offset
+	p.write([]byte(fmt.Sprintf("%d 0 obj\n", num)))
+}
+
+func (p *pdfWriter) streamObj(num int, dict string, data []byte) {
+	p.beginObj(num)
+	p.write([]byte(fmt.Sprintf("<< %s /Length %d >>\nstream\n", dict, len(data))))
+	p.write(data)
+	p.write([]byte("\nendstream\nendobj\n"))
+}
+
+// AddGrayPage: Página do tamanho da imagem na resolução dpi
+func (p *pdfWriter) AddGrayPage(pix []byte, width, height, dpi int) error {
+	var img bytes.Buffer
+	zw := zlib.NewWriter(&img)
+	if _, err := zw.Write(pix[:width*height]); err != nil {
+		return fmt.Errorf("compress page: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("compress page: %w", err)
+	}
+
+	imgObj, contentObj, pageObj := p.nextObj, p.nextObj+1, p.nextObj+2
+	p.nextObj += 3
+
+	// Tamanho em pontos (1/72")
+	wPt := float64(width) * 72 / float64(dpi)
+	hPt := float64(height) * 72 / float64(dpi)
+
+	p.streamObj(imgObj, fmt.Sprintf(
+		"/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceGray /BitsPerComponent 8 /Filter /FlateDecode",
+		width, height), img.Bytes())
+
+	content := fmt.Sprintf("q %.2f 0 0 %.2f 0 0 cm /Im0 Do Q", wPt, hPt)
+	p.streamObj(contentObj, "", []byte(content))
+
+	p.beginObj(pageObj)
+	p.write([]byte(fmt.Sprintf(
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /XObject << /Im0 %d 0 R >> >> /Contents %d 0 R >>\nendobj\n",
+		wPt, hPt, imgObj, contentObj)))
+
+	p.pages = append(p.pages, pageObj)
+	return nil
+}
+
+func (p *pdfWriter) Close() error {
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+
+	var kids bytes.Buffer
+	for _, obj := range p.pages {
+		fmt.Fprintf(&kids, "%d 0 R ", obj)
+	}
+	p.beginObj(1)
+	p.write([]byte("<< /Type /Catalog /Pages 2 0 R >>\nendobj\n"))
+	p.beginObj(2)
+	p.write([]byte(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>\nendobj\n", kids.String(), len(p.pages))))
+
+	// Tabela xref (entradas de 20 bytes)
+	xref := p.offset
+	p.write([]byte(fmt.Sprintf("xref\n0 %d\n0000000000 65535 f \n", p.nextObj)))
+	for obj := 1; obj < p.nextObj; obj++ {
+		p.write([]byte(fmt.Sprintf("%010d 00000 n \n", p.offsets[obj])))
+	}
+	p.write([]byte(fmt.Sprintf("trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", p.nextObj, xref)))
+
+	if err := p.w.Flush(); err != nil {
+		p.f.Close()
+		return fmt.Errorf("write pdf: %w", err)
+	}
+	return p.f.Close()
+}
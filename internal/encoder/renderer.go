@@ -0,0 +1,189 @@
+package encoder
+
+import "fmt"
+
+// FrameRenderer: Desenha o stream de um frame (header + shards + padding)
+// direto no buffer de pixels. Compartilhado pelo encoder local e pelos
+// workers do cluster; não cria estruturas por macro pixel.
+type FrameRenderer struct {
+	Config        FrameConfig
+	cols, rows    int
+	bitsPerCell   uint
+	symbols       [4]uint8  // Cinza por símbolo (binário usa 0-1)
+	calibrationPx []uint8   // Uma linha da barra de calibração (cinza)
+	blocks        [][]uint8 // Pixels por símbolo (modulação DCT)
+}
+
+// NewFrameRenderer: Pré-calcula níveis e a linha da barra de calibração
+func NewFrameRenderer(cfg FrameConfig) *FrameRenderer {
+	cols, rows := cfg.GridSize()
+	r := &FrameRenderer{
+		Config:      cfg,
+		cols:        cols,
+		rows:        rows,
+		bitsPerCell: 2,
+		symbols:     grayLevels,
+	}
+	if cfg.GrayLevels == 2 {
+		r.bitsPerCell = 1
+		r.symbols = [4]uint8{binaryLevels[0], binaryLevels[1], binaryLevels[1], binaryLevels[1]}
+	}
+	if cfg.Modulation == ModulationDCT {
+		r.bitsPerCell = uint(cfg.BitsPerCell())
+		r.blocks = dctBlocks(cfg.MacroSize)
+	}
+
+	// Barra estática (Preto/Branco/Preto/Branco)
+	sectionWidth := cfg.Width / 4
+	r.calibrationPx = make([]uint8, cfg.Width)
+	for x := range r.calibrationPx {
+		if (x >= sectionWidth && x < sectionWidth*2) || x >= sectionWidth*3 {
+			r.calibrationPx[x] = 255
+		}
+	}
+	return r
+}
+
+// StreamSize: Bytes de stream que preenchem a grade
+func (r *FrameRenderer) StreamSize() int {
+	return r.Config.GridBytes()
+}
+
+// RenderGray: Desenha o frame em um buffer cinza (1 byte/pixel, stride = Width)
+func (r *FrameRenderer) RenderGray(dst, stream []byte) error {
+	return r.render(dst, stream, 1)
+}
+
+// RenderRGBA: Desenha o frame em um buffer RGBA (4 bytes/pixel, stride = Width*4)
+func (r *FrameRenderer) RenderRGBA(dst, stream []byte) error {
+	return r.render(dst, stream, 4)
+}
+
+// render: Cada linha da grade é montada uma vez em cinza e expandida para a
+// linha de pixels; as demais linhas do macro pixel são cópias dela.
+// Áreas fora da grade (sobras à direita/abaixo) ficam em preto opaco.
+func (r *FrameRenderer) render(dst, stream []byte, bpp int) error {
+	cfg := r.Config
+	stride := cfg.Width * bpp
+	if len(dst) < stride*cfg.Height {
+		return fmt.Errorf("frame buffer too small: %d bytes < %d", len(dst), stride*cfg.Height)
+	}
+	if len(stream) > r.StreamSize() {
+		return fmt.Errorf("data too large for frame: %d bytes > %d max", len(stream), r.StreamSize())
+	}
+
+	// Barra de calibração
+	r.expandRow(dst[:stride], r.calibrationPx, bpp)
+	for y := 1; y < CalibrationBarHeight; y++ {
+		copy(dst[y*stride:(y+1)*stride], dst[:stride])
+	}
+
+	// Linhas da grade
+	gray := make([]uint8, cfg.Width) // Sobras à direita permanecem 0
+	if r.blocks != nil {
+		r.renderBlocks(dst, stream, gray, bpp)
+	} else {
+		r.renderCells(dst, stream, gray, bpp)
+	}
+
+	// Sobras abaixo da grade
+	bottom := CalibrationBarHeight + r.rows*cfg.MacroSize
+	if bottom < cfg.Height {
+		clear(gray)
+		r.expandRow(dst[bottom*stride:(bottom+1)*stride], gray, bpp)
+		for y := bottom + 1; y < cfg.Height; y++ {
+			copy(dst[y*stride:(y+1)*stride], dst[bottom*stride:(bottom+1)*stride])
+		}
+	}
+	return nil
+}
+
+// renderCells: Macro pixels de cor única (níveis de cinza)
+func (r *FrameRenderer) renderCells(dst, stream, gray []uint8, bpp int) {
+	cfg := r.Config
+	stride := cfg.Width * bpp
+	perByte := 8 / int(r.bitsPerCell)
+	mask := byte(1<<r.bitsPerCell - 1)
+	cell := 0
+	for row := 0; row < r.rows; row++ {
+		for col := 0; col < r.cols; col++ {
+			if byteIdx := cell / perByte; byteIdx < len(stream) {
+				shift := uint(perByte-1-cell%perByte) * r.bitsPerCell
+				gray[col*cfg.MacroSize] = r.symbols[(stream[byteIdx]>>shift)&mask]
+			} else {
+				gray[col*cfg.MacroSize] = 0 // Células além do stream
+			}
+			cell++
+		}
+		for col := 0; col < r.cols; col++ {
+			start := col * cfg.MacroSize
+			v := gray[start]
+			for k := 1; k < cfg.MacroSize; k++ {
+				gray[start+k] = v
+			}
+		}
+
+		top := CalibrationBarHeight + row*cfg.MacroSize
+		first := dst[top*stride : (top+1)*stride]
+		r.expandRow(first, gray, bpp)
+		for y := top + 1; y < top+cfg.MacroSize && y < cfg.Height; y++ {
+			copy(dst[y*stride:(y+1)*stride], first)
+		}
+	}
+}
+
+// renderBlocks: Blocos DCT pré-calculados por símbolo; cada linha de pixels
+// da linha de blocos é montada a partir das linhas dos blocos
+func (r *FrameRenderer) renderBlocks(dst, stream, gray []uint8, bpp int) {
+	cfg := r.Config
+	size := cfg.MacroSize
+	stride := cfg.Width * bpp
+	bits := int(r.bitsPerCell)
+	symbols := make([]int, r.cols)
+	for row := 0; row < r.rows; row++ {
+		for col := range symbols {
+			symbols[col] = -1 // Blocos além do stream: cinza liso
+			if off := (row*r.cols + col) * bits; off+bits <= len(stream)*8 {
+				symbols[col] = streamBits(stream, off, bits)
+			}
+		}
+		top := CalibrationBarHeight + row*size
+		for y := 0; y < size; y++ {
+			for col, sym := range symbols {
+				line := gray[col*size : (col+1)*size]
+				if sym < 0 {
+					for i := range line {
+						line[i] = DCTDC
+					}
+				} else {
+					copy(line, r.blocks[sym][y*size:(y+1)*size])
+				}
+			}
+			r.expandRow(dst[(top+y)*stride:(top+y+1)*stride], gray, bpp)
+		}
+	}
+}
+
+// streamBits: n bits do stream a partir do bit off (MSB primeiro)
+func streamBits(stream []byte, off, n int) int {
+	v := 0
+	for i := off; i < off+n; i++ {
+		v = v<<1 | int(stream[i/8]>>(7-i%8)&1)
+	}
+	return v
+}
+
+// expandRow: Linha cinza -> linha do buffer (RGBA com alfa opaco)
+func (r *FrameRenderer) expandRow(dst, gray []uint8, bpp int) {
+	if bpp == 1 {
+		copy(dst, gray)
+		return
+	}
+	for x, v := range gray {
+		off := x * 4
+		dst[off] = v
+		dst[off+1] = v
+		dst[off+2] = v
+		dst[off+3] = 255
+	}
+}
@@ -0,0 +1,199 @@
+package encoder
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/hkdf"
+)
+
+// ScrambleKeySize: Chave do embaralhamento (derivada da chave de arquivo)
+const ScrambleKeySize = 32
+
+// scrambleSampleSize: Bytes do corpo embaralhado que formam o nonce da
+// máscara do header
+const scrambleSampleSize = chacha20.NonceSize
+
+// leadScrambleKey: Chave pública dos frames iniciais, que levam o header
+// NCC3: o decoder os lê antes de abrir a chave de arquivo. Eles continuam
+// com cara de ruído, mas quem tem o ncc os desembaralha.
+var leadScrambleKey = sha256.Sum256([]byte("ncc scramble lead v1"))
+
+// Scrambler: Embaralhamento com chave do stream de cada frame (-scramble).
+// O corpo (shards + padding) recebe XOR com ChaCha20 de nonce = campos do
+// header; o header recebe XOR com uma máscara cujo nonce é uma amostra do
+// corpo já embaralhado (o decoder a lê sem conhecer o índice do frame). Por
+// fim os símbolos das células são permutados (permutação fixa por chave).
+// Sem a chave o frame é ruído uniforme, com ~50% de células claras.
+type Scrambler struct {
+	bodyKey   []byte
+	headerKey []byte
+	permKey   []byte
+	bits      int // Bits por célula (símbolo permutado)
+
+	lead       *Scrambler // Chave pública dos frames < leadFrames (nil = nenhum)
+	leadFrames int
+
+	mu    sync.Mutex
+	perms map[int][]int // Símbolos -> permutação
+}
+
+// NewScrambler: Scrambler da chave para a grade de cfg. Os primeiros
+// leadFrames frames (header NCC3) usam a chave pública.
+func NewScrambler(key []byte, leadFrames int, cfg FrameConfig) (*Scrambler, error) {
+	if len(key) != ScrambleKeySize {
+		return nil, fmt.Errorf("scramble key: got %d bytes, need %d", len(key), ScrambleKeySize)
+	}
+	s, err := newScrambler(key, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if leadFrames > 0 {
+		if s.lead, err = NewLeadScrambler(cfg); err != nil {
+			return nil, err
+		}
+		s.leadFrames = leadFrames
+	}
+	return s, nil
+}
+
+// NewLeadScrambler: Só a chave pública (decode dos frames iniciais, antes
+// da chave de arquivo)
+func NewLeadScrambler(cfg FrameConfig) (*Scrambler, error) {
+	return newScrambler(leadScrambleKey[:], cfg)
+}
+
+func newScrambler(key []byte, cfg FrameConfig) (*Scrambler, error) {
+	s := &Scrambler{bits: cfg.BitsPerCell(), perms: make(map[int][]int)}
+	for _, out := range []struct {
+		dst  *[]byte
+		info string
+	}{{&s.bodyKey, "ncc scramble body"}, {&s.headerKey, "ncc scramble header"}, {&s.permKey, "ncc scramble perm"}} {
+		*out.dst = make([]byte, chacha20.KeySize)
+		if _, err := io.ReadFull(hkdf.New(sha256.New, key, nil, []byte(out.info)), *out.dst); err != nil {
+			return nil, fmt.Errorf("derive key: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// LeadFrames: Frames iniciais com a chave pública
+func (s *Scrambler) LeadFrames() int {
+	return s.leadFrames
+}
+
+// ScrambleLeadFrames: Frames que levam os primeiros n bytes do payload (o
+// header NCC3), na grade de cfg com ecc
+func ScrambleLeadFrames(cfg FrameConfig, ecc ECCConfig, n int) int {
+	capacityFrame0 := cfg.CapacityPerFrame(ecc, true)
+	capacityOthers := cfg.CapacityPerFrame(ecc, false)
+	if n <= capacityFrame0 || capacityOthers <= 0 {
+		return 1
+	}
+	return 1 + (n-capacityFrame0+capacityOthers-1)/capacityOthers
+}
+
+// Scramble: Stream do frame (Frame.Bytes) -> stream embaralhado, em novo slice
+func (s *Scrambler) Scramble(stream []byte) []byte {
+	if s.lead != nil && len(stream) >= 8 && int64(binary.BigEndian.Uint32(stream[4:8])) < int64(s.leadFrames) {
+		return s.lead.Scramble(stream)
+	}
+	out := append([]byte(nil), stream...)
+	if len(out) < FrameHeaderSizeBytes+scrambleSampleSize {
+		return out
+	}
+	header, body := out[:FrameHeaderSizeBytes], out[FrameHeaderSizeBytes:]
+	xorKeyStream(s.bodyKey, header[4:16], body)
+	xorKeyStream(s.headerKey, body[:scrambleSampleSize], header)
+	return s.permute(out, false)
+}
+
+// Unscramble: Inverso de Scramble sobre os bytes lidos de um frame. Erros de
+// leitura continuam nas mesmas posições (o ECC os corrige); um erro no header
+// ou na amostra produz um header inválido. Sem o magic com esta chave, tenta
+// a pública (frame inicial).
+func (s *Scrambler) Unscramble(stream []byte) []byte {
+	out := s.unscramble(stream)
+	if s.lead != nil && !bytes.HasPrefix(out, frameMagic[:]) {
+		if lead := s.lead.unscramble(stream); bytes.HasPrefix(lead, frameMagic[:]) {
+			return lead
+		}
+	}
+	return out
+}
+
+func (s *Scrambler) unscramble(stream []byte) []byte {
+	out := s.permute(stream, true)
+	if len(out) < FrameHeaderSizeBytes+scrambleSampleSize {
+		return out
+	}
+	header, body := out[:FrameHeaderSizeBytes], out[FrameHeaderSizeBytes:]
+	xorKeyStream(s.headerKey, body[:scrambleSampleSize], header)
+	xorKeyStream(s.bodyKey, header[4:16], body)
+	return out
+}
+
+// xorKeyStream: buf ^= ChaCha20(key, nonce)
+func xorKeyStream(key, nonce, buf []byte) {
+	c, _ := chacha20.NewUnauthenticatedCipher(key, nonce)
+	c.XORKeyStream(buf, buf)
+}
+
+// permute: Move o símbolo i (bits MSB primeiro, um por célula) para a
+// posição perm[i]; inverse desfaz. Bits além do último símbolo inteiro
+// ficam no lugar.
+func (s *Scrambler) permute(stream []byte, inverse bool) []byte {
+	n := len(stream) * 8 / s.bits
+	perm := s.permutation(n)
+	out := append([]byte(nil), stream...)
+	for i, j := range perm {
+		src, dst := i, j
+		if inverse {
+			src, dst = j, i
+		}
+		setStreamBits(out, dst*s.bits, s.bits, streamBits(stream, src*s.bits, s.bits))
+	}
+	return out
+}
+
+// permutation: Fisher-Yates com fluxo da chave (cache por tamanho de grade)
+func (s *Scrambler) permutation(n int) []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if perm, ok := s.perms[n]; ok {
+		return perm
+	}
+
+	rnd := make([]byte, 8*n)
+	var nonce [chacha20.NonceSize]byte
+	binary.BigEndian.PutUint32(nonce[8:], uint32(n))
+	xorKeyStream(s.permKey, nonce[:], rnd)
+	perm := make([]int, n)
+	for i := range perm {
+		perm[i] = i
+	}
+	for i := n - 1; i > 0; i-- {
+		j := int(binary.BigEndian.Uint64(rnd[8*i:]) % uint64(i+1))
+		perm[i], perm[j] = perm[j], perm[i]
+	}
+	s.perms[n] = perm
+	return perm
+}
+
+// setStreamBits: Grava os n bits de v a partir do bit off (MSB primeiro)
+func setStreamBits(stream []byte, off, n, v int) {
+	for i := 0; i < n; i++ {
+		bit := off + i
+		mask := byte(0x80 >> (bit % 8))
+		if v>>(n-1-i)&1 == 1 {
+			stream[bit/8] |= mask
+		} else {
+			stream[bit/8] &^= mask
+		}
+	}
+}
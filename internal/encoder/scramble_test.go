@@ -0,0 +1,78 @@
+package encoder
+
+import (
+	"bytes"
+	"testing"
+)
+
+// scrambleTestStream: Stream do frame index com data
+func scrambleTestStream(t *testing.T, cfg FrameConfig, index int, data []byte) []byte {
+	t.Helper()
+	ecc, err := NewECCEncoder(NewECCConfig("low"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewFrame(cfg, ecc, index, data, 4, 0, [32]byte{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := f.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stream
+}
+
+func TestScramblerLeadFrames(t *testing.T) {
+	cfg := PresetFrameConfig("default")
+	key := bytes.Repeat([]byte{7}, ScrambleKeySize)
+	s, err := NewScrambler(key, 2, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lead, err := NewLeadScrambler(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewScrambler(bytes.Repeat([]byte{8}, ScrambleKeySize), 2, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for index := 0; index < 4; index++ {
+		stream := scrambleTestStream(t, cfg, index, []byte("payload"))
+		scrambled := s.Scramble(stream)
+		if bytes.Equal(scrambled[:FrameHeaderSizeBytes], stream[:FrameHeaderSizeBytes]) {
+			t.Fatalf("frame %d: header em claro", index)
+		}
+		if !bytes.Equal(s.Unscramble(scrambled), stream) {
+			t.Fatalf("frame %d: Unscramble não inverte Scramble", index)
+		}
+		// Frames iniciais abrem com a chave pública, os demais só com a chave
+		isLead := bytes.Equal(lead.Unscramble(scrambled), stream)
+		if isLead != (index < 2) {
+			t.Errorf("frame %d: chave pública abre = %v", index, isLead)
+		}
+		if index >= 2 && bytes.HasPrefix(other.Unscramble(scrambled), frameMagic[:]) {
+			t.Errorf("frame %d: aberto com outra chave", index)
+		}
+	}
+}
+
+func TestScrambleLeadFrames(t *testing.T) {
+	cfg := PresetFrameConfig("default")
+	ecc := NewECCConfig("low")
+	capacityFrame0 := cfg.CapacityPerFrame(ecc, true)
+	capacityOthers := cfg.CapacityPerFrame(ecc, false)
+	for _, tc := range []struct{ n, want int }{
+		{0, 1},
+		{capacityFrame0, 1},
+		{capacityFrame0 + 1, 2},
+		{capacityFrame0 + capacityOthers, 2},
+		{capacityFrame0 + capacityOthers + 1, 3},
+	} {
+		if got := ScrambleLeadFrames(cfg, ecc, tc.n); got != tc.want {
+			t.Errorf("ScrambleLeadFrames(%d) = %d, want %d", tc.n, got, tc.want)
+		}
+	}
+}
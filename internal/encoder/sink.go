@@ -0,0 +1,286 @@
+package encoder
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// FrameSink: Destino dos frames renderizados, na ordem, em cinza
+// (Width x Height, 1 byte/pixel). Close finaliza o arquivo e pode ser
+// chamado mais de uma vez.
+type FrameSink interface {
+	WriteFrame(pix []byte) error
+	Close() error
+}
+
+// Container sem compressão do NCC (.nccv): header fixo + frames Y brutos
+const (
+	NCCVHeaderSize   = 24
+	nccvVersion      = 1
+	nccvPixFmtGray   = 0
+	nccvFrameCountAt = 20 // Offset do contador de frames (preenchido no Close)
+)
+
+// NCCVMagic: Assinatura do container .nccv
+var NCCVMagic = [4]byte{'N', 'C', 'C', 'V'}
+
+// SinkKind: Tipo de destino pela saída (extensão ou diretório)
+// "png" (sequência em diretório), "y4m", "nccv", "pdf" ou "ffmpeg"
+func SinkKind(outputPath string) string {
+	if info, err := os.Stat(outputPath); err == nil && info.IsDir() {
+		return "png"
+	}
+	if strings.HasSuffix(outputPath, "/") || strings.HasSuffix(outputPath, string(os.PathSeparator)) {
+		return "png"
+	}
+	switch strings.ToLower(filepath.Ext(outputPath)) {
+	case "":
+		return "png"
+	case ".y4m":
+		return "y4m"
+	case ".nccv":
+		return "nccv"
+	case ".pdf":
+		return "pdf"
+	}
+	return "ffmpeg"
+}
+
+// OpenSink: Abre o destino adequado à saída. Sequência PNG, Y4M, NCCV e
+// páginas (PDF/PNG) são Go puro; demais extensões (mp4, mkv, avi...) passam
+// pelo FFmpeg.
+// Todo destino confere o tamanho de cada frame (sizedSink).
+func (ve *VideoEncoder) OpenSink(outputPath string, totalFrames int) (FrameSink, error) {
+	sink, err := ve.openSink(outputPath, totalFrames)
+	if err != nil {
+		return nil, err
+	}
+	return &sizedSink{FrameSink: sink, size: ve.FrameCfg.Width * ve.FrameCfg.Height}, nil
+}
+
+func (ve *VideoEncoder) openSink(outputPath string, totalFrames int) (FrameSink, error) {
+	cfg := ve.FrameCfg
+	if ve.Pages || SinkKind(outputPath) == "pdf" {
+		return newPageSink(outputPath, cfg, totalFrames)
+	}
+	switch SinkKind(outputPath) {
+	case "png":
+		return newPNGSink(outputPath, cfg, ve.Preset == "fast")
+	case "y4m":
+		return newY4MSink(outputPath, cfg)
+	case "nccv":
+		return newNCCVSink(outputPath, cfg)
+	}
+
+	cmd, stdin, err := ve.StartFFmpegPipe(outputPath, totalFrames)
+	if err != nil {
+		return nil, err
+	}
+	return &ffmpegSink{
+		cmd:   cmd,
+		stdin: stdin,
+		w:     bufio.NewWriterSize(stdin, 4*1024*1024), // Buffer de escrita (4MB)
+	}, nil
+}
+
+// sizedSink: Recusa frames que não têm Width x Height bytes (ex.: worker de
+// outra versão); gravados, deslocariam todos os frames seguintes
+type sizedSink struct {
+	FrameSink
+	size int
+}
+
+func (s *sizedSink) WriteFrame(pix []byte) error {
+	if len(pix) != s.size {
+		return fmt.Errorf("frame com %d bytes, esperado %d (cinza, 1 byte/pixel)", len(pix), s.size)
+	}
+	return s.FrameSink.WriteFrame(pix)
+}
+
+// ffmpegSink: Pipe rawvideo gray para o FFmpeg
+type ffmpegSink struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	w      *bufio.Writer
+	closed bool
+}
+
+func (s *ffmpegSink) WriteFrame(pix []byte) error {
+	_, err := s.w.Write(pix)
+	return err
+}
+
+func (s *ffmpegSink) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	flushErr := s.w.Flush()
+	s.stdin.Close() // EOF
+	if err := s.cmd.Wait(); err != nil {
+		return fmt.Errorf("ffmpeg finish: %w", err)
+	}
+	return flushErr
+}
+
+// pngSink: Sequência frame_00001.png, frame_00002.png... (mesmo padrão do
+// extrator do decoder)
+type pngSink struct {
+	dir   string
+	img   *image.Gray
+	enc   png.Encoder
+	count int
+}
+
+func newPNGSink(dir string, cfg FrameConfig, fast bool) (*pngSink, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create frame dir: %w", err)
+	}
+	s := &pngSink{
+		dir: dir,
+		img: &image.Gray{Stride: cfg.Width, Rect: image.Rect(0, 0, cfg.Width, cfg.Height)},
+	}
+	if fast {
+		s.enc.CompressionLevel = png.BestSpeed
+	}
+	return s, nil
+}
+
+func (s *pngSink) WriteFrame(pix []byte) error {
+	s.count++
+	path := filepath.Join(s.dir, fmt.Sprintf("frame_%05d.png", s.count))
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", path, err)
+	}
+	s.img.Pix = pix
+	if err := s.enc.Encode(f, s.img); err != nil {
+		f.Close()
+		return fmt.Errorf("encode png: %w", err)
+	}
+	return f.Close()
+}
+
+func (s *pngSink) Close() error {
+	return nil
+}
+
+// y4mSink: YUV4MPEG2 4:2:0 (planos de croma neutros em 128). Legível pelo
+// FFmpeg e pela maioria dos players sem codec.
+type y4mSink struct {
+	f      *os.File
+	w      *bufio.Writer
+	chroma []byte
+	closed bool
+}
+
+func newY4MSink(path string, cfg FrameConfig) (*y4mSink, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("create y4m: %w", err)
+	}
+	s := &y4mSink{f: f, w: bufio.NewWriterSize(f, 4*1024*1024)}
+
+	cw, ch := (cfg.Width+1)/2, (cfg.Height+1)/2
+	s.chroma = make([]byte, 2*cw*ch)
+	for i := range s.chroma {
+		s.chroma[i] = 128
+	}
+
+	header := fmt.Sprintf("YUV4MPEG2 W%d H%d F%d:1 Ip A1:1 C420jpeg XCOLORRANGE=FULL\n",
+		cfg.Width, cfg.Height, cfg.FPS)
+	if _, err := s.w.WriteString(header); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("write y4m header: %w", err)
+	}
+	return s, nil
+}
+
+func (s *y4mSink) WriteFrame(pix []byte) error {
+	if _, err := s.w.WriteString("FRAME\n"); err != nil {
+		return err
+	}
+	if _, err := s.w.Write(pix); err != nil {
+		return err
+	}
+	_, err := s.w.Write(s.chroma)
+	return err
+}
+
+func (s *y4mSink) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if err := s.w.Flush(); err != nil {
+		s.f.Close()
+		return fmt.Errorf("flush y4m: %w", err)
+	}
+	return s.f.Close()
+}
+
+// nccvSink: Container próprio sem compressão.
+// Header (24 bytes, big endian): Magic "NCCV" | Versão u8 | PixFmt u8 |
+// Reservado u16 | Width u32 | Height u32 | FPS u32 | Frames u32
+type nccvSink struct {
+	f      *os.File
+	w      *bufio.Writer
+	count  uint32
+	closed bool
+}
+
+func newNCCVSink(path string, cfg FrameConfig) (*nccvSink, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("create nccv: %w", err)
+	}
+
+	var header [NCCVHeaderSize]byte
+	copy(header[0:4], NCCVMagic[:])
+	header[4] = nccvVersion
+	header[5] = nccvPixFmtGray
+	binary.BigEndian.PutUint32(header[8:12], uint32(cfg.Width))
+	binary.BigEndian.PutUint32(header[12:16], uint32(cfg.Height))
+	binary.BigEndian.PutUint32(header[16:20], uint32(cfg.FPS))
+	// Frames (20:24) é preenchido no Close
+
+	if _, err := f.Write(header[:]); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("write nccv header: %w", err)
+	}
+	return &nccvSink{f: f, w: bufio.NewWriterSize(f, 4*1024*1024)}, nil
+}
+
+func (s *nccvSink) WriteFrame(pix []byte) error {
+	if _, err := s.w.Write(pix); err != nil {
+		return err
+	}
+	s.count++
+	return nil
+}
+
+func (s *nccvSink) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if err := s.w.Flush(); err != nil {
+		s.f.Close()
+		return fmt.Errorf("flush nccv: %w", err)
+	}
+	var count [4]byte
+	binary.BigEndian.PutUint32(count[:], s.count)
+	if _, err := s.f.WriteAt(count[:], nccvFrameCountAt); err != nil {
+		s.f.Close()
+		return fmt.Errorf("write nccv frame count: %w", err)
+	}
+	return s.f.Close()
+}
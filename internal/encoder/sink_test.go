@@ -0,0 +1,52 @@
+package encoder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSinkKind(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range []struct{ path, want string }{
+		{dir, "png"},
+		{"frames/", "png"},
+		{"frames", "png"},
+		{"out.Y4M", "y4m"},
+		{"out.nccv", "nccv"},
+		{"out.pdf", "pdf"},
+		{"out.mp4", "ffmpeg"},
+	} {
+		if got := SinkKind(tc.path); got != tc.want {
+			t.Errorf("SinkKind(%q) = %s, want %s", tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestSinkRejectsMisSizedFrames(t *testing.T) {
+	ve := &VideoEncoder{FrameCfg: DefaultFrameConfig()}
+	path := filepath.Join(t.TempDir(), "out.nccv")
+	sink, err := ve.OpenSink(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := ve.FrameCfg.Width * ve.FrameCfg.Height
+	if err := sink.WriteFrame(make([]byte, size-1)); err == nil {
+		t.Error("frame menor aceito")
+	}
+	for i := 0; i < 2; i++ {
+		if err := sink.WriteFrame(make([]byte, size)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() < int64(2*size) {
+		t.Errorf(".nccv com %d bytes para 2 frames de %d", info.Size(), size)
+	}
+}
@@ -0,0 +1,483 @@
+// Package stego esconde o payload (já comprimido e cifrado) em um vídeo de
+// cobertura comum, modulando coeficientes DCT de banda média dos blocos
+// 8x8 de luminância (Koch-Zhao: sinal de C(2,3) - C(3,2)).
+//
+// Cada frame leva um header curto (índice do frame + tamanho do payload)
+// repetido várias vezes e uma janela do payload codificado; o payload se
+// repete ciclicamente até o fim da cobertura e o decoder soma as leituras
+// (decisão suave). Posições e sinais vêm da senha: sem ela os blocos
+// modulados são indistinguíveis do resto.
+package stego
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+
+	"github.com/klauspost/reedsolomon"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Parâmetros do canal
+const (
+	BlockSize       = 8
+	HeaderSize      = 16   // Magic "NS" | Versão | Reservado | Frame u32 | Tamanho u32 | CRC32
+	HeaderRepeats   = 8    // Cópias do header em cada frame
+	DefaultStrength = 32.0 // |C(2,3) - C(3,2)| mínimo após embutir
+	softClip        = 96.0 // Limite de uma leitura na soma (texturas fortes)
+
+	dataShards   = 16
+	parityShards = 32 // ECC pesado: 2/3 dos shards podem ser perdidos
+	maxShardSize = 64
+	shardCRCSize = 4
+
+	headerVersion = 1
+)
+
+var headerMagic = [2]byte{'N', 'S'}
+
+// Coeficientes do par (horizontal, vertical)
+var coeffA, coeffB = [2]int{2, 3}, [2]int{3, 2}
+
+// pattern: Base DCT de A menos base de B. Projeção do bloco = C(A) - C(B);
+// somar k*pattern muda a diferença em 2k (norma² = 2).
+var pattern = func() (p [BlockSize * BlockSize]float64) {
+	basis := func(u, v, x, y int) float64 {
+		au, av := 0.5, 0.5
+		if u == 0 {
+			au = math.Sqrt(0.125)
+		}
+		if v == 0 {
+			av = math.Sqrt(0.125)
+		}
+		return au * av *
+			math.Cos(float64((2*x+1)*u)*math.Pi/16) *
+			math.Cos(float64((2*y+1)*v)*math.Pi/16)
+	}
+	for y := 0; y < BlockSize; y++ {
+		for x := 0; x < BlockSize; x++ {
+			p[y*BlockSize+x] = basis(coeffA[0], coeffA[1], x, y) - basis(coeffB[0], coeffB[1], x, y)
+		}
+	}
+	return p
+}()
+
+// keys: Chaves do canal derivadas da senha
+type keys struct {
+	perm   []byte // Permutação blocos -> slots
+	whiten []byte // Sinais pseudoaleatórios por slot
+}
+
+func deriveKeys(password string) (keys, error) {
+	// Argon2id com sal fixo: a chave só decide posições/sinais, o payload
+	// já vem cifrado com sal aleatório
+	master := argon2.IDKey([]byte(password), []byte("ncc-stego-v1"), 3, 64*1024, 4, 32)
+	var k keys
+	for _, out := range []struct {
+		dst  *[]byte
+		info string
+	}{{&k.perm, "ncc stego perm"}, {&k.whiten, "ncc stego whiten"}} {
+		*out.dst = make([]byte, chacha20.KeySize)
+		if _, err := io.ReadFull(hkdf.New(sha256.New, master, nil, []byte(out.info)), *out.dst); err != nil {
+			return keys{}, fmt.Errorf("derive key: %w", err)
+		}
+	}
+	return k, nil
+}
+
+// keystream: Fluxo ChaCha20 (chave, nonce numérico)
+func keystream(key []byte, nonce uint64, n int) []byte {
+	var iv [chacha20.NonceSize]byte
+	binary.BigEndian.PutUint64(iv[4:], nonce)
+	c, _ := chacha20.NewUnauthenticatedCipher(key, iv[:])
+	out := make([]byte, n)
+	c.XORKeyStream(out, out)
+	return out
+}
+
+// headerNonce: Sinais do header independem do frame (o índice está nele)
+const headerNonce = math.MaxUint64
+
+// channel: Slots de um tamanho de frame (permutação fixa por vídeo)
+type channel struct {
+	keys
+	W, H         int
+	perm         []int // Slot -> bloco (raster)
+	headerSlots  int
+	payloadSlots int
+	headerSigns  []byte
+}
+
+func newChannel(k keys, w, h int) (*channel, error) {
+	bw, bh := w/BlockSize, h/BlockSize
+	blocks := bw * bh
+	headerSlots := HeaderSize * 8 * HeaderRepeats
+	if blocks <= headerSlots {
+		return nil, fmt.Errorf("vídeo %dx%d pequeno demais para stego (%d blocos 8x8)", w, h, blocks)
+	}
+
+	// Fisher-Yates com fluxo da chave
+	rnd := keystream(k.perm, 0, 8*blocks)
+	perm := make([]int, blocks)
+	for i := range perm {
+		perm[i] = i
+	}
+	for i := blocks - 1; i > 0; i-- {
+		j := int(binary.BigEndian.Uint64(rnd[8*i:]) % uint64(i+1))
+		perm[i], perm[j] = perm[j], perm[i]
+	}
+
+	return &channel{
+		keys:         k,
+		W:            w,
+		H:            h,
+		perm:         perm,
+		headerSlots:  headerSlots,
+		payloadSlots: blocks - headerSlots,
+		headerSigns:  keystream(k.whiten, headerNonce, headerSlots/8),
+	}, nil
+}
+
+// blockAt: Canto do bloco do slot
+func (ch *channel) blockAt(slot int) (int, int) {
+	b := ch.perm[slot]
+	bw := ch.W / BlockSize
+	return (b % bw) * BlockSize, (b / bw) * BlockSize
+}
+
+// project: C(A) - C(B) do bloco em (x0, y0)
+func (ch *channel) project(luma []byte, x0, y0 int) float64 {
+	var d float64
+	for y := 0; y < BlockSize; y++ {
+		row := luma[(y0+y)*ch.W+x0:]
+		for x := 0; x < BlockSize; x++ {
+			d += float64(row[x]) * pattern[y*BlockSize+x]
+		}
+	}
+	return d
+}
+
+// embedBit: Força o sinal da diferença com margem strength (só altera o
+// bloco se preciso)
+func (ch *channel) embedBit(luma []byte, x0, y0 int, bit bool, strength float64) {
+	s := -1.0
+	if bit {
+		s = 1
+	}
+	d := ch.project(luma, x0, y0)
+	if s*d >= strength {
+		return
+	}
+	k := s * (strength - s*d) / 2
+	for y := 0; y < BlockSize; y++ {
+		row := luma[(y0+y)*ch.W+x0:]
+		for x := 0; x < BlockSize; x++ {
+			v := float64(row[x]) + k*pattern[y*BlockSize+x]
+			row[x] = uint8(math.Max(0, math.Min(255, math.Round(v))))
+		}
+	}
+}
+
+func bitAt(b []byte, i int) bool {
+	return b[i/8]&(0x80>>(i%8)) != 0
+}
+
+// frameHeader: Header de sincronia de cada frame
+type frameHeader struct {
+	Frame  uint32
+	Length uint32
+}
+
+func (h frameHeader) encode() []byte {
+	buf := make([]byte, HeaderSize)
+	copy(buf[0:2], headerMagic[:])
+	buf[2] = headerVersion
+	binary.BigEndian.PutUint32(buf[4:8], h.Frame)
+	binary.BigEndian.PutUint32(buf[8:12], h.Length)
+	binary.BigEndian.PutUint32(buf[12:16], crc32.ChecksumIEEE(buf[:12]))
+	return buf
+}
+
+func decodeFrameHeader(buf []byte) (frameHeader, bool) {
+	if buf[0] != headerMagic[0] || buf[1] != headerMagic[1] || buf[2] != headerVersion {
+		return frameHeader{}, false
+	}
+	if crc32.ChecksumIEEE(buf[:12]) != binary.BigEndian.Uint32(buf[12:16]) {
+		return frameHeader{}, false
+	}
+	return frameHeader{
+		Frame:  binary.BigEndian.Uint32(buf[4:8]),
+		Length: binary.BigEndian.Uint32(buf[8:12]),
+	}, true
+}
+
+// layout: Shards por grupo Reed-Solomon para um payload de n bytes
+func layout(n int) (shardSize, groups int) {
+	shardSize = min(maxShardSize, max(1, (n+dataShards-1)/dataShards))
+	groups = max(1, (n+dataShards*shardSize-1)/(dataShards*shardSize))
+	return shardSize, groups
+}
+
+// codedSize: Bytes do payload codificado (grupos x shards x (dados + CRC))
+func codedSize(n int) int {
+	shardSize, groups := layout(n)
+	return groups * (dataShards + parityShards) * (shardSize + shardCRCSize)
+}
+
+// encodePayload: Grupos RS 16+32; cada shard leva CRC32 para virar
+// apagamento quando corrompido
+func encodePayload(payload []byte) ([]byte, error) {
+	shardSize, groups := layout(len(payload))
+	enc, err := reedsolomon.New(dataShards, parityShards)
+	if err != nil {
+		return nil, fmt.Errorf("create RS encoder: %w", err)
+	}
+
+	out := make([]byte, 0, codedSize(len(payload)))
+	for g := 0; g < groups; g++ {
+		shards := make([][]byte, dataShards+parityShards)
+		for i := range shards {
+			shards[i] = make([]byte, shardSize)
+			if i < dataShards {
+				start := (g*dataShards + i) * shardSize
+				if start < len(payload) {
+					copy(shards[i], payload[start:])
+				}
+			}
+		}
+		if err := enc.Encode(shards); err != nil {
+			return nil, fmt.Errorf("RS encode: %w", err)
+		}
+		for _, s := range shards {
+			out = append(out, s...)
+			out = binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(s))
+		}
+	}
+	return out, nil
+}
+
+// decodePayload: Shards com CRC inválido são descartados e reconstruídos
+func decodePayload(coded []byte, n int) ([]byte, error) {
+	shardSize, groups := layout(n)
+	enc, err := reedsolomon.New(dataShards, parityShards)
+	if err != nil {
+		return nil, fmt.Errorf("create RS decoder: %w", err)
+	}
+
+	wire := shardSize + shardCRCSize
+	out := make([]byte, 0, groups*dataShards*shardSize)
+	var lost int
+	for g := 0; g < groups; g++ {
+		shards := make([][]byte, dataShards+parityShards)
+		for i := range shards {
+			w := coded[(g*len(shards)+i)*wire:][:wire]
+			if crc32.ChecksumIEEE(w[:shardSize]) == binary.BigEndian.Uint32(w[shardSize:]) {
+				shards[i] = w[:shardSize]
+			} else {
+				lost++
+			}
+		}
+		if err := enc.ReconstructData(shards); err != nil {
+			return nil, fmt.Errorf("grupo %d irrecuperável (vídeo recomprimido demais?): %w", g, err)
+		}
+		for _, s := range shards[:dataShards] {
+			out = append(out, s...)
+		}
+	}
+	if lost > 0 {
+		fmt.Printf("🔧 %d de %d shards corrigidos pelo Reed-Solomon\n", lost, groups*(dataShards+parityShards))
+	}
+	return out[:n], nil
+}
+
+// Embed: Esconde payload no vídeo de cobertura e grava outputPath. A
+// cobertura precisa ter slots para ao menos uma cópia do payload; cópias
+// extras aumentam a robustez.
+func Embed(coverPath, outputPath string, payload []byte, password string) error {
+	if password == "" {
+		return fmt.Errorf("stego requer senha (a chave decide onde os bits ficam)")
+	}
+	k, err := deriveKeys(password)
+	if err != nil {
+		return err
+	}
+	coded, err := encodePayload(payload)
+	if err != nil {
+		return err
+	}
+	codedBits := len(coded) * 8
+
+	cover, err := openVideo(coverPath)
+	if err != nil {
+		return err
+	}
+	defer cover.Close()
+
+	ch, err := newChannel(k, cover.W, cover.H)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("🎞️  Cobertura %dx%d: %d bits de payload por frame, payload codificado %d bits (%d frames por cópia)\n",
+		ch.W, ch.H, ch.payloadSlots, codedBits, (codedBits+ch.payloadSlots-1)/ch.payloadSlots)
+
+	out, err := createVideo(outputPath, coverPath, cover.header)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	frames := 0
+	for {
+		frame, err := cover.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		ch.embedFrame(frame[:ch.W*ch.H], frames, coded, len(payload))
+		if err := out.WriteFrame(frame); err != nil {
+			return fmt.Errorf("write frame: %w", err)
+		}
+		frames++
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	copies := float64(frames*ch.payloadSlots) / float64(codedBits)
+	if copies < 1 {
+		os.Remove(outputPath)
+		return fmt.Errorf("cobertura curta demais: %d frames levam %.0f%% do payload; use um vídeo com ao menos %d frames",
+			frames, copies*100, (codedBits+ch.payloadSlots-1)/ch.payloadSlots)
+	}
+	fmt.Printf("✅ Payload embutido em %d frames (%.1f cópias)\n", frames, copies)
+	if copies < 3 {
+		fmt.Println("⚠️  Poucas cópias: recompressão forte pode destruir o payload. Prefira uma cobertura mais longa.")
+	}
+	return nil
+}
+
+// embedFrame: Header (cópias fixas) + janela cíclica do payload codificado
+func (ch *channel) embedFrame(luma []byte, index int, coded []byte, length int) {
+	header := frameHeader{Frame: uint32(index), Length: uint32(length)}.encode()
+	for slot := 0; slot < ch.headerSlots; slot++ {
+		bit := bitAt(header, slot%(HeaderSize*8)) != bitAt(ch.headerSigns, slot)
+		x, y := ch.blockAt(slot)
+		ch.embedBit(luma, x, y, bit, DefaultStrength)
+	}
+
+	codedBits := len(coded) * 8
+	signs := keystream(ch.whiten, uint64(index), (ch.payloadSlots+7)/8)
+	start := index * ch.payloadSlots % codedBits
+	for j := 0; j < ch.payloadSlots; j++ {
+		pos := (start + j) % codedBits
+		bit := bitAt(coded, pos) != bitAt(signs, j)
+		x, y := ch.blockAt(ch.headerSlots + j)
+		ch.embedBit(luma, x, y, bit, DefaultStrength)
+	}
+}
+
+// Extract: Lê o payload de um vídeo stego com a senha usada no Embed
+func Extract(inputPath, password string) ([]byte, error) {
+	if password == "" {
+		return nil, fmt.Errorf("stego requer senha")
+	}
+	k, err := deriveKeys(password)
+	if err != nil {
+		return nil, err
+	}
+
+	video, err := openVideo(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	defer video.Close()
+
+	ch, err := newChannel(k, video.W, video.H)
+	if err != nil {
+		return nil, err
+	}
+
+	var soft []float64 // Soma das leituras por bit codificado
+	var length, frames, synced int
+	for {
+		frame, err := video.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		frames++
+
+		hdr, ok := ch.readHeader(frame)
+		if !ok {
+			continue
+		}
+		if soft == nil {
+			length = int(hdr.Length)
+			soft = make([]float64, codedSize(length)*8)
+		} else if int(hdr.Length) != length {
+			continue
+		}
+		ch.accumulate(frame, int(hdr.Frame), soft)
+		synced++
+	}
+	if soft == nil {
+		return nil, fmt.Errorf("nenhum frame com header stego válido em %d frames (senha errada ou vídeo sem payload)", frames)
+	}
+	fmt.Printf("🔎 %d de %d frames sincronizados, payload de %d bytes\n", synced, frames, length)
+
+	coded := make([]byte, len(soft)/8)
+	for i, v := range soft {
+		if v > 0 {
+			coded[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return decodePayload(coded, length)
+}
+
+// readHeader: Soma as cópias do header do frame e confere o CRC
+func (ch *channel) readHeader(frame []byte) (frameHeader, bool) {
+	var sums [HeaderSize * 8]float64
+	for slot := 0; slot < ch.headerSlots; slot++ {
+		x, y := ch.blockAt(slot)
+		d := clampSoft(ch.project(frame, x, y))
+		if bitAt(ch.headerSigns, slot) {
+			d = -d
+		}
+		sums[slot%len(sums)] += d
+	}
+	buf := make([]byte, HeaderSize)
+	for i, v := range sums {
+		if v > 0 {
+			buf[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return decodeFrameHeader(buf)
+}
+
+// accumulate: Soma as leituras da janela do payload deste frame
+func (ch *channel) accumulate(frame []byte, index int, soft []float64) {
+	signs := keystream(ch.whiten, uint64(index), (ch.payloadSlots+7)/8)
+	start := index * ch.payloadSlots % len(soft)
+	for j := 0; j < ch.payloadSlots; j++ {
+		x, y := ch.blockAt(ch.headerSlots + j)
+		d := clampSoft(ch.project(frame, x, y))
+		if bitAt(signs, j) {
+			d = -d
+		}
+		soft[(start+j)%len(soft)] += d
+	}
+}
+
+func clampSoft(d float64) float64 {
+	return math.Max(-softClip, math.Min(softClip, d))
+}
@@ -0,0 +1,69 @@
+package stego
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeCover: Cobertura .y4m 4:2:0 com textura aleatória suave
+func writeCover(t *testing.T, path string, w, h, frames int) {
+	t.Helper()
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "YUV4MPEG2 W%d H%d F30:1 Ip A1:1 C420jpeg\n", w, h)
+	rng := rand.New(rand.NewSource(7))
+	cw, ch := (w+1)/2, (h+1)/2
+	for f := 0; f < frames; f++ {
+		buf.WriteString("FRAME\n")
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				buf.WriteByte(byte(64 + (x+y+f)%128 + rng.Intn(16)))
+			}
+		}
+		buf.Write(bytes.Repeat([]byte{128}, 2*cw*ch))
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEmbedExtractRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	cover := filepath.Join(dir, "cover.y4m")
+	writeCover(t, cover, 320, 240, 90)
+	payload := make([]byte, 200)
+	rand.New(rand.NewSource(8)).Read(payload)
+
+	stego := filepath.Join(dir, "stego.y4m")
+	if err := Embed(cover, stego, payload, "senha"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Extract(stego, "senha")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Errorf("payload difere: %d bytes, want %d", len(got), len(payload))
+	}
+
+	// Outra senha: posições e sinais diferentes, nenhum header válido
+	if _, err := Extract(stego, "outra"); err == nil {
+		t.Error("payload extraído com outra senha")
+	}
+}
+
+func TestEmbedShortCover(t *testing.T) {
+	dir := t.TempDir()
+	cover := filepath.Join(dir, "cover.y4m")
+	writeCover(t, cover, 64, 64, 1)
+	stego := filepath.Join(dir, "stego.y4m")
+	if err := Embed(cover, stego, make([]byte, 4000), "senha"); err == nil {
+		t.Fatal("cobertura curta demais aceita")
+	}
+	if _, err := os.Stat(stego); err == nil {
+		t.Error("saída de cobertura curta ficou no disco")
+	}
+}
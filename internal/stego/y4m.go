@@ -0,0 +1,224 @@
+package stego
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// y4mReader: Frames YUV 4:2:0 completos (Y, U, V contíguos). O vídeo de
+// cobertura é convertido para yuv420p pelo FFmpeg; .y4m é lido direto.
+type y4mReader struct {
+	r      *bufio.Reader
+	header string // Linha de header original (reusada na saída)
+	W, H   int
+	frame  []byte
+}
+
+func newY4MReader(r io.Reader) (*y4mReader, error) {
+	br := bufio.NewReaderSize(r, 4*1024*1024)
+	line, err := br.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("read y4m header: %w", err)
+	}
+	if !strings.HasPrefix(line, "YUV4MPEG2 ") {
+		return nil, fmt.Errorf("not a YUV4MPEG2 stream")
+	}
+
+	y := &y4mReader{r: br, header: line}
+	chroma := "420"
+	for _, field := range strings.Fields(line)[1:] {
+		switch field[0] {
+		case 'W':
+			y.W, _ = strconv.Atoi(field[1:])
+		case 'H':
+			y.H, _ = strconv.Atoi(field[1:])
+		case 'C':
+			chroma = field[1:]
+		}
+	}
+	if y.W <= 0 || y.H <= 0 {
+		return nil, fmt.Errorf("invalid y4m size %dx%d", y.W, y.H)
+	}
+	if !strings.HasPrefix(chroma, "420") {
+		return nil, fmt.Errorf("y4m chroma C%s não suportado (use 4:2:0)", chroma)
+	}
+	cw, ch := (y.W+1)/2, (y.H+1)/2
+	y.frame = make([]byte, y.W*y.H+2*cw*ch)
+	return y, nil
+}
+
+// Next: Próximo frame (buffer reutilizado); io.EOF no fim
+func (y *y4mReader) Next() ([]byte, error) {
+	line, err := y.r.ReadString('\n')
+	if err == io.EOF && line == "" {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read y4m frame header: %w", err)
+	}
+	if !strings.HasPrefix(line, "FRAME") {
+		return nil, fmt.Errorf("invalid y4m frame marker %q", strings.TrimSpace(line))
+	}
+	if _, err := io.ReadFull(y.r, y.frame); err != nil {
+		return nil, fmt.Errorf("read y4m frame: %w", err)
+	}
+	return y.frame, nil
+}
+
+// coverReader: Vídeo de cobertura/stego decodificado em frames 4:2:0
+type coverReader struct {
+	*y4mReader
+	src io.Closer
+	cmd *exec.Cmd
+}
+
+func openVideo(path string) (*coverReader, error) {
+	if strings.EqualFold(filepath.Ext(path), ".y4m") {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("open %s: %w", path, err)
+		}
+		y, err := newY4MReader(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return &coverReader{y4mReader: y, src: f}, nil
+	}
+
+	cmd := exec.Command(findFFmpeg(),
+		"-v", "error",
+		"-i", path,
+		"-f", "yuv4mpegpipe",
+		"-pix_fmt", "yuv420p",
+		"-strict", "-1",
+		"pipe:1",
+	)
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start ffmpeg: %w", err)
+	}
+	y, err := newY4MReader(stdout)
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, err
+	}
+	return &coverReader{y4mReader: y, src: stdout, cmd: cmd}, nil
+}
+
+func (c *coverReader) Close() error {
+	if c.cmd != nil {
+		// Leitura pode ter parado antes do fim: não esperar o pipe drenar
+		c.cmd.Process.Kill()
+		c.cmd.Wait()
+		return nil
+	}
+	return c.src.Close()
+}
+
+// videoWriter: Saída do vídeo stego. .y4m é gravado direto; demais
+// extensões vão para o FFmpeg (H.264 CRF baixo + áudio da cobertura).
+type videoWriter struct {
+	w      *bufio.Writer
+	dst    io.WriteCloser
+	cmd    *exec.Cmd
+	closed bool
+}
+
+func createVideo(outputPath, coverPath, header string) (*videoWriter, error) {
+	var vw *videoWriter
+	if strings.EqualFold(filepath.Ext(outputPath), ".y4m") {
+		f, err := os.Create(outputPath)
+		if err != nil {
+			return nil, fmt.Errorf("create %s: %w", outputPath, err)
+		}
+		vw = &videoWriter{dst: f}
+	} else {
+		cmd := exec.Command(findFFmpeg(),
+			"-y", "-v", "error",
+			"-f", "yuv4mpegpipe", "-i", "pipe:0",
+			"-i", coverPath,
+			"-map", "0:v:0", "-map", "1:a?",
+			"-c:v", "libx264", "-preset", "medium", "-crf", "16",
+			"-pix_fmt", "yuv420p",
+			"-c:a", "aac", "-b:a", "192k",
+			"-shortest",
+			outputPath,
+		)
+		cmd.Stderr = os.Stderr
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, fmt.Errorf("start ffmpeg: %w", err)
+		}
+		vw = &videoWriter{dst: stdin, cmd: cmd}
+	}
+	vw.w = bufio.NewWriterSize(vw.dst, 4*1024*1024)
+	if _, err := vw.w.WriteString(header); err != nil {
+		vw.Close()
+		return nil, fmt.Errorf("write y4m header: %w", err)
+	}
+	return vw, nil
+}
+
+func (vw *videoWriter) WriteFrame(frame []byte) error {
+	if _, err := vw.w.WriteString("FRAME\n"); err != nil {
+		return err
+	}
+	_, err := vw.w.Write(frame)
+	return err
+}
+
+func (vw *videoWriter) Close() error {
+	if vw.closed {
+		return nil
+	}
+	vw.closed = true
+	flushErr := vw.w.Flush()
+	closeErr := vw.dst.Close()
+	if vw.cmd != nil {
+		if err := vw.cmd.Wait(); err != nil {
+			return fmt.Errorf("ffmpeg finish: %w", err)
+		}
+	}
+	if flushErr != nil {
+		return flushErr
+	}
+	return closeErr
+}
+
+// findFFmpeg: Busca FFmpeg no PATH e locais comuns
+func findFFmpeg() string {
+	if path, err := exec.LookPath("ffmpeg"); err == nil {
+		return path
+	}
+
+	locations := []string{
+		`C:\ffmpeg\bin\ffmpeg.exe`,
+		`C:\Program Files\ffmpeg\bin\ffmpeg.exe`,
+		`C:\Program Files (x86)\ffmpeg\bin\ffmpeg.exe`,
+		filepath.Join(os.Getenv("LOCALAPPDATA"), "Microsoft", "WinGet", "Links", "ffmpeg.exe"),
+		filepath.Join(os.Getenv("USERPROFILE"), "scoop", "shims", "ffmpeg.exe"),
+	}
+
+	for _, loc := range locations {
+		if _, err := os.Stat(loc); err == nil {
+			return loc
+		}
+	}
+
+	return "ffmpeg"
+}
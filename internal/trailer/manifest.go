@@ -0,0 +1,32 @@
+package trailer
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+const manifestSize = 8 + 32
+
+// Manifest: Tamanho e SHA-256 do arquivo original. Só é gravado sem senha
+// (com senha o AEAD já autentica e o manifest exporia o hash em claro).
+type Manifest struct {
+	Size   uint64
+	SHA256 [32]byte
+}
+
+// Record: Manifest como registro do trailer
+func (m Manifest) Record() Record {
+	v := binary.BigEndian.AppendUint64(nil, m.Size)
+	return Record{Type: RecordManifest, Value: append(v, m.SHA256[:]...)}
+}
+
+// DecodeManifest: Inverso de Record
+func DecodeManifest(b []byte) (Manifest, error) {
+	var m Manifest
+	if len(b) < manifestSize {
+		return m, errors.New("manifest truncado")
+	}
+	m.Size = binary.BigEndian.Uint64(b[:8])
+	copy(m.SHA256[:], b[8:manifestSize])
+	return m, nil
+}
@@ -0,0 +1,125 @@
+package trailer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"ncc/pkg/utils"
+)
+
+const (
+	minMerkleChunk  = 4096
+	maxMerkleLeaves = 2000 // Registro TLV limitado a 64 KiB
+)
+
+// MerkleIndex: Folhas da árvore de Merkle sobre chunks fixos do payload
+// (depois da cifra). A raiz é assinada (-sign): cada chunk íntegro é
+// provado sozinho, mesmo com frames perdidos em outra parte.
+type MerkleIndex struct {
+	ChunkSize int
+	Leaves    [][32]byte
+}
+
+// Range: Intervalo [Start, End) de bytes do payload
+type Range struct {
+	Start, End int64
+}
+
+// MerkleChunkSize: Menor potência de 2 (>= 4 KiB) que mantém o número de
+// folhas dentro do registro
+func MerkleChunkSize(payloadSize int64) int {
+	size := minMerkleChunk
+	for int64(size)*maxMerkleLeaves < payloadSize {
+		size *= 2
+	}
+	return size
+}
+
+// NewMerkleIndex: Folhas do payload completo
+func NewMerkleIndex(payload []byte) MerkleIndex {
+	size := MerkleChunkSize(int64(len(payload)))
+	leaves, _ := utils.MerkleLeaves(bytes.NewReader(payload), size)
+	return MerkleIndex{ChunkSize: size, Leaves: leaves}
+}
+
+// Root: Raiz da árvore (coberta pela assinatura)
+func (m MerkleIndex) Root() [32]byte {
+	return utils.MerkleRoot(m.Leaves)
+}
+
+// Record: Tamanho do chunk u32 | Folhas (32 bytes cada)
+func (m MerkleIndex) Record() Record {
+	v := binary.BigEndian.AppendUint32(nil, uint32(m.ChunkSize))
+	for _, leaf := range m.Leaves {
+		v = append(v, leaf[:]...)
+	}
+	return Record{Type: RecordMerkle, Value: v}
+}
+
+// DecodeMerkleIndex: Inverso de Record
+func DecodeMerkleIndex(b []byte) (MerkleIndex, error) {
+	if len(b) < 4 || (len(b)-4)%32 != 0 {
+		return MerkleIndex{}, errors.New("índice Merkle truncado")
+	}
+	m := MerkleIndex{ChunkSize: int(binary.BigEndian.Uint32(b[:4]))}
+	if m.ChunkSize < minMerkleChunk {
+		return MerkleIndex{}, fmt.Errorf("índice Merkle com chunk inválido: %d", m.ChunkSize)
+	}
+	for b = b[4:]; len(b) > 0; b = b[32:] {
+		m.Leaves = append(m.Leaves, [32]byte(b[:32]))
+	}
+	return m, nil
+}
+
+// Check: Intervalos do payload (lido de r, size bytes) cujos chunks não
+// conferem com as folhas, já unidos quando adjacentes
+func (m MerkleIndex) Check(r io.Reader, size int64) ([]Range, error) {
+	leaves, err := utils.MerkleLeaves(r, m.ChunkSize)
+	if err != nil {
+		return nil, err
+	}
+	if len(leaves) != len(m.Leaves) {
+		return nil, fmt.Errorf("payload com %d chunks, índice Merkle tem %d", len(leaves), len(m.Leaves))
+	}
+	var bad []Range
+	for i, leaf := range leaves {
+		if leaf == m.Leaves[i] {
+			continue
+		}
+		start := int64(i) * int64(m.ChunkSize)
+		end := min(start+int64(m.ChunkSize), size)
+		if n := len(bad); n > 0 && bad[n-1].End == start {
+			bad[n-1].End = end
+			continue
+		}
+		bad = append(bad, Range{Start: start, End: end})
+	}
+	return bad, nil
+}
+
+// MergeRanges: União ordenada dos intervalos (sobrepostos ou adjacentes
+// viram um só)
+func MergeRanges(sets ...[]Range) []Range {
+	var all []Range
+	for _, s := range sets {
+		all = append(all, s...)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Start < all[j].Start })
+	var out []Range
+	for _, r := range all {
+		if n := len(out); n > 0 && r.Start <= out[n-1].End {
+			out[n-1].End = max(out[n-1].End, r.End)
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+func (r Range) String() string {
+	return fmt.Sprintf("%d-%d", r.Start, r.End)
+}
@@ -0,0 +1,133 @@
+// Package trailer implementa o trailer TLV anexado ao payload (assinatura,
+// manifest...). Fica fora da criptografia e é localizado pelo rodapé no fim
+// dos dados: Registros... | Tamanho dos registros u32 | Magic "NCCT".
+package trailer
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Tipos de registro
+const (
+	RecordSignature = 1 // Ed25519: Chave pública | Assinatura | Descritor
+	RecordManifest  = 2 // Arquivo original (sem senha): Tamanho | SHA-256
+	RecordMerkle    = 3 // Chunks do payload: Tamanho do chunk | Folhas da árvore
+	RecordMerkleMAC = 4 // Payload cifrado: HMAC da raiz com chave da chave de arquivo
+)
+
+const (
+	footerSize    = 8
+	recordHdrSize = 3 // Tipo u8 | Tamanho u16
+	maxTrailer    = 1 << 20
+)
+
+// Magic: Assinatura do rodapé
+var Magic = [4]byte{'N', 'C', 'C', 'T'}
+
+// Record: Registro TLV do trailer
+type Record struct {
+	Type  byte
+	Value []byte
+}
+
+// Find: Primeiro registro do tipo (nil se ausente)
+func Find(records []Record, typ byte) *Record {
+	for i := range records {
+		if records[i].Type == typ {
+			return &records[i]
+		}
+	}
+	return nil
+}
+
+// Append: payload | registros | rodapé
+func Append(payload []byte, records []Record) ([]byte, error) {
+	out := append([]byte(nil), payload...)
+	start := len(out)
+	for _, r := range records {
+		if len(r.Value) > 0xFFFF {
+			return nil, fmt.Errorf("trailer record %d too large: %d bytes", r.Type, len(r.Value))
+		}
+		out = append(out, r.Type)
+		out = binary.BigEndian.AppendUint16(out, uint16(len(r.Value)))
+		out = append(out, r.Value...)
+	}
+	out = binary.BigEndian.AppendUint32(out, uint32(len(out)-start))
+	return append(out, Magic[:]...), nil
+}
+
+// parse: Registros a partir dos bytes entre payload e rodapé
+func parse(b []byte) ([]Record, error) {
+	var records []Record
+	for len(b) > 0 {
+		if len(b) < recordHdrSize {
+			return nil, errors.New("trailer truncado")
+		}
+		n := int(binary.BigEndian.Uint16(b[1:3]))
+		if len(b) < recordHdrSize+n {
+			return nil, errors.New("trailer truncado")
+		}
+		records = append(records, Record{Type: b[0], Value: b[recordHdrSize : recordHdrSize+n]})
+		b = b[recordHdrSize+n:]
+	}
+	return records, nil
+}
+
+// Split: Separa payload e registros em memória. ok = false sem trailer.
+func Split(data []byte) (payload []byte, records []Record, ok bool, err error) {
+	if len(data) < footerSize || [4]byte(data[len(data)-4:]) != Magic {
+		return data, nil, false, nil
+	}
+	size := int(binary.BigEndian.Uint32(data[len(data)-footerSize:]))
+	if size > len(data)-footerSize {
+		return nil, nil, true, errors.New("trailer com tamanho inválido")
+	}
+	end := len(data) - footerSize
+	records, err = parse(data[end-size : end])
+	return data[:end-size], records, true, err
+}
+
+// ReadFile: Registros do trailer no fim do arquivo e tamanho do payload
+// (sem ler o payload). ok = false sem trailer.
+func ReadFile(path string) (records []Record, payloadSize int64, ok bool, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, false, err
+	}
+	if info.Size() < footerSize {
+		return nil, info.Size(), false, nil
+	}
+
+	var footer [footerSize]byte
+	if _, err := f.ReadAt(footer[:], info.Size()-footerSize); err != nil {
+		return nil, 0, false, err
+	}
+	if [4]byte(footer[4:]) != Magic {
+		return nil, info.Size(), false, nil
+	}
+	size := int64(binary.BigEndian.Uint32(footer[:4]))
+	if size > maxTrailer || size > info.Size()-footerSize {
+		return nil, 0, true, errors.New("trailer com tamanho inválido")
+	}
+	payloadSize = info.Size() - footerSize - size
+	body := make([]byte, size)
+	if _, err := f.ReadAt(body, payloadSize); err != nil && err != io.EOF {
+		return nil, 0, true, err
+	}
+	records, err = parse(body)
+	return records, payloadSize, true, err
+}
+
+// StripFile: Remove o trailer do arquivo (deixa só o payload)
+func StripFile(path string, payloadSize int64) error {
+	return os.Truncate(path, payloadSize)
+}
@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"io"
+)
+
+// Domain prefixes: a leaf hash never collides with an inner node
+const (
+	merkleLeaf = 0x00
+	merkleNode = 0x01
+)
+
+// MerkleLeaf hashes one payload chunk as a tree leaf
+func MerkleLeaf(chunk []byte) [32]byte {
+	h := sha256.New()
+	h.Write([]byte{merkleLeaf})
+	h.Write(chunk)
+	return [32]byte(h.Sum(nil))
+}
+
+// MerkleRoot computes the root over the leaf hashes (an odd node is
+// promoted to the next level unchanged)
+func MerkleRoot(leaves [][32]byte) [32]byte {
+	if len(leaves) == 0 {
+		return MerkleLeaf(nil)
+	}
+	level := append([][32]byte(nil), leaves...)
+	for len(level) > 1 {
+		next := level[:0]
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			var buf [1 + 64]byte
+			buf[0] = merkleNode
+			copy(buf[1:], level[i][:])
+			copy(buf[33:], level[i+1][:])
+			next = append(next, sha256.Sum256(buf[:]))
+		}
+		level = next
+	}
+	return level[0]
+}
+
+// MerkleLeaves hashes r in chunks of chunkSize (the last one may be shorter)
+func MerkleLeaves(r io.Reader, chunkSize int) ([][32]byte, error) {
+	var leaves [][32]byte
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			leaves = append(leaves, MerkleLeaf(buf[:n]))
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return leaves, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}